@@ -1,6 +1,7 @@
 package soaap
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
@@ -23,55 +24,57 @@ type Analyser func(*CallGraph) (CallGraph, error)
 //   * vuln: the callgraph of previously-vulnerable code
 //   * privaccess: the call-and-data-flow graph of access to private data
 //
-func ApplyAnalysis(spec string, cg *CallGraph, results *Results,
-	depth int, report func(string)) (CallGraph, error) {
+func ApplyAnalysis(ctx context.Context, spec string, cg *CallGraph, results *Results,
+	depth int, progress ProgressReporter) (CallGraph, error) {
+
+	if err := ctx.Err(); err != nil {
+		return CallGraph{}, err
+	}
 
 	union := func(g *CallGraph) (CallGraph, error) {
-		err := cg.Union(*g)
+		err := cg.UnionWithProgress(*g, progress)
 		return *cg, err
 	}
 
 	switch spec[0] {
 	case '+':
-		report("Adding " + spec[1:])
-		return extractAndCombine(spec[1:], results, report, union)
+		return extractAndCombine(ctx, spec[1:], results, progress, union)
 
 	case '^':
-		report(fmt.Sprintf("Intersecting (depth %d) with %s", depth, spec[1:]))
-		return extractAndCombine(spec[1:], results, report,
+		return extractAndCombine(ctx, spec[1:], results, progress,
 			func(g *CallGraph) (CallGraph, error) {
-				return cg.Intersect(*g, depth, true)
+				return cg.IntersectWithProgress(*g, depth, true, progress)
 			})
 
 	case '.':
-		report(fmt.Sprintf("Adding intersection (depth %d) with %s", depth, spec[1:]))
-		return extractAndCombine(spec[1:], results, report,
+		return extractAndCombine(ctx, spec[1:], results, progress,
 			func(g *CallGraph) (CallGraph, error) {
-				err := cg.AddIntersecting(*g, depth)
+				err := cg.AddIntersectingWithProgress(*g, depth, progress)
 				return *cg, err
 			})
 
 	case ':':
-		report("Filtering with '" + spec + "'")
+		progress.Start(0, "Filtering with '"+spec+"'")
+		defer progress.Finish()
 		return Filter(*cg, spec[1:])
 
 	default:
-		report("Adding " + spec)
-		return extractAndCombine(spec, results, report, union)
+		return extractAndCombine(ctx, spec, results, progress, union)
 	}
 }
 
-func extractAndCombine(graphname string, r *Results, report func(string),
+func extractAndCombine(ctx context.Context, graphname string, r *Results, progress ProgressReporter,
 	analyse Analyser) (CallGraph, error) {
 
-	g, err := r.ExtractGraph(graphname, report)
+	g, err := r.ExtractGraph(ctx, graphname, progress)
 	if err != nil {
 		return CallGraph{}, err
 	}
 
 	nodes, edges, flows := g.Size()
-	report(fmt.Sprintf("'%s': %d nodes, %d edges, %d flows",
+	progress.Start(0, fmt.Sprintf("'%s': %d nodes, %d edges, %d flows",
 		graphname, nodes, edges, flows))
+	progress.Finish()
 
 	return analyse(&g)
 }
@@ -4,6 +4,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/CTSRD-SOAAP/gosoaap"
 )
@@ -17,11 +19,51 @@ var (
 	intersectionDepth = flag.Int("intersection-depth", 3,
 		"how many calls to trace back from a leaf node when looking"+
 			" for call graph intersections")
+
+	codecFlag = flag.String("codec", "",
+		"graph codec to use for input/output: gob, json or proto"+
+			" (default: inferred from each file's extension)")
+
+	fetchTimeout = flag.Duration("timeout", 30*time.Second,
+		"timeout for fetching a remote http(s):// input")
+
+	quiet = flag.Bool("quiet", false, "suppress progress reporting")
+
+	fetchHeaders headerList
 )
 
+// headerList collects repeated -header flags into a set of HTTP headers for
+// soaap.HTTPFetcher.
+type headerList []string
+
+func (h *headerList) String() string { return strings.Join(*h, ", ") }
+
+func (h *headerList) Set(value string) error {
+	*h = append(*h, value)
+	return nil
+}
+
+func (h headerList) toMap() map[string]string {
+	m := make(map[string]string, len(h))
+	for _, entry := range h {
+		k, v, ok := strings.Cut(entry, ":")
+		if !ok {
+			continue
+		}
+		m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return m
+}
+
 func main() {
+	flag.Var(&fetchHeaders, "header",
+		"extra HTTP header (\"Key: Value\") to send when fetching a remote input; may be repeated")
+
 	flag.Parse()
 
+	soaap.RegisterFetcher("http", &soaap.HTTPFetcher{Timeout: *fetchTimeout, Headers: fetchHeaders.toMap()})
+	soaap.RegisterFetcher("https", &soaap.HTTPFetcher{Timeout: *fetchTimeout, Headers: fetchHeaders.toMap()})
+
 	//
 	// Open input files:
 	//
@@ -32,12 +74,17 @@ func main() {
 
 	graphs := make([]soaap.CallGraph, 0, 2)
 	for _, filename := range flag.Args() {
-		f, err := os.Open(filename)
+		f, err := soaap.OpenInput(filename)
 		if err != nil {
 			die("error opening '%s': %s", filename, err)
 		}
 
-		graph, err := soaap.LoadGraph(f, report)
+		codec, err := resolveCodec(*codecFlag, filename)
+		if err != nil {
+			die("%s", err)
+		}
+
+		graph, err := codec.Decode(f)
 		if err != nil {
 			die("error loading graph from '%s': %s", filename, err)
 		}
@@ -56,17 +103,19 @@ func main() {
 	//
 	// Apply the requested combining operation:
 	//
+	progress := soaap.NewProgressReporter(os.Stderr, *quiet, func(s string) { fmt.Println(s) })
+
 	graph := graphs[0]
 	for _, g := range graphs[1:] {
 		switch *operation {
 		case "addintersecting":
-			err = graph.AddIntersecting(g, *intersectionDepth)
+			err = graph.AddIntersectingWithProgress(g, *intersectionDepth, progress)
 
 		case "intersection":
-			graph, err = graph.Intersect(g, *intersectionDepth, true)
+			graph, err = graph.IntersectWithProgress(g, *intersectionDepth, true, progress)
 
 		case "union":
-			err = graph.Union(g)
+			err = graph.UnionWithProgress(g, progress)
 
 		default:
 			die("Unknown combining operation: '%s'", *operation)
@@ -81,12 +130,35 @@ func main() {
 	fmt.Printf("Final graph has %d nodes, %d edges and %d flows.\n",
 		nodes, edges, flows)
 
-	err = graph.Save(out)
+	codec, err := resolveCodec(*codecFlag, *output)
+	if err != nil {
+		die("%s", err)
+	}
+
+	err = codec.Encode(out, graph)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error saving: %s\n", err)
 	}
 }
 
+// resolveCodec picks a soaap.GraphCodec by explicit name (gob, json or
+// proto), or by filename extension (see soaap.CodecForFile) when explicit
+// is empty.
+func resolveCodec(explicit, filename string) (soaap.GraphCodec, error) {
+	switch explicit {
+	case "":
+		return soaap.CodecForFile(filename), nil
+	case "gob":
+		return soaap.GobCodec{}, nil
+	case "json":
+		return soaap.JSONCodec{}, nil
+	case "proto":
+		return soaap.ProtoCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown codec %q (want gob, json or proto)", explicit)
+	}
+}
+
 func die(format string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, format+"\n", args...)
 	os.Exit(1)
@@ -94,13 +166,9 @@ func die(format string, args ...interface{}) {
 
 func printUsage() {
 	fmt.Fprintf(os.Stderr,
-		"Usage:  soaap-combine [options] <input files>\n\n")
+		"Usage:  soaap-combine [options] <input files>\n\n"+
+			"Each <input file> may also be an http(s):// or s3:// URL.\n\n")
 
 	fmt.Fprintf(os.Stderr, "Options:\n")
 	flag.PrintDefaults()
 }
-
-func report(progress string) {
-	fmt.Println(progress)
-	os.Stdout.Sync()
-}
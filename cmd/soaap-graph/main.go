@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/CTSRD-SOAAP/gosoaap"
 )
@@ -20,6 +24,29 @@ func (a Analyses) String() string {
 	return strings.Join(a, ", ")
 }
 
+// headerList collects repeated -header flags into a set of HTTP headers for
+// soaap.HTTPFetcher.
+type headerList []string
+
+func (h *headerList) String() string { return strings.Join(*h, ", ") }
+
+func (h *headerList) Set(value string) error {
+	*h = append(*h, value)
+	return nil
+}
+
+func (h headerList) toMap() map[string]string {
+	m := make(map[string]string, len(h))
+	for _, entry := range h {
+		k, v, ok := strings.Cut(entry, ":")
+		if !ok {
+			continue
+		}
+		m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return m
+}
+
 var (
 	intersectionDepth = flag.Int("intersection-depth", 3,
 		"how many calls to trace back from a leaf node when looking"+
@@ -43,11 +70,43 @@ func main() {
 
 	output := flag.String("output", "-", "output file")
 
-	binout := flag.Bool("binary", false, "write binary output")
+	binout := flag.Bool("binary", false, "write binary output (gob codec, unless -codec says otherwise)")
+	codecFlag := flag.String("codec", "",
+		"graph codec to use for binary input/output: gob, json or proto"+
+			" (default: inferred from the input/output file's extension)")
+	graphInput := flag.Bool("graph-input", false,
+		"treat the input file as a previously-saved call graph rather than raw SOAAP results")
 	simplify := flag.Bool("simplify", false, "simplify callgraph")
+	interactive := flag.Bool("interactive", false,
+		"explore the graph in a pprof-style REPL instead of writing it out")
+	findings := flag.Bool("findings", false,
+		"print a ranked Findings report (see soaap.Results.Findings) instead of building a graph")
+	reachableFromEntries := flag.Bool("reachable-from-entries", false,
+		"filter the graph down to what's reachable from a detected program entry point"+
+			" (see soaap.EntryPointConfig/CallGraph.ReachableFrom)")
+
+	fetchTimeout := flag.Duration("timeout", 30*time.Second,
+		"timeout for fetching a remote http(s):// input")
+	var fetchHeaders headerList
+	flag.Var(&fetchHeaders, "header",
+		"extra HTTP header (\"Key: Value\") to send when fetching a remote input; may be repeated")
+
+	quiet := flag.Bool("quiet", false, "suppress progress reporting")
+
+	watch := flag.Bool("watch", false,
+		"re-run the load/apply/simplify/output pipeline whenever the input file changes")
+	watchDelay := flag.Duration("watch-delay", 250*time.Millisecond,
+		"debounce interval for -watch")
+	serve := flag.String("serve", "",
+		"with -watch and a dot/svg -output, serve the rendered SVG (with live reload) at this address (e.g. :8080)")
 
 	flag.Parse()
 
+	progress := soaap.NewProgressReporter(os.Stderr, *quiet, func(s string) { fmt.Println(s) })
+
+	soaap.RegisterFetcher("http", &soaap.HTTPFetcher{Timeout: *fetchTimeout, Headers: fetchHeaders.toMap()})
+	soaap.RegisterFetcher("https", &soaap.HTTPFetcher{Timeout: *fetchTimeout, Headers: fetchHeaders.toMap()})
+
 	var input string
 	switch len(flag.Args()) {
 	case 0:
@@ -61,123 +120,358 @@ func main() {
 		return
 	}
 
-	//
-	// Load input file:
-	//
-	var f *os.File
-	var err error
+	opts := renderOptions{
+		legend:               *legend,
+		graphInput:           *graphInput,
+		analyses:             []string(analyses),
+		intersectionDepth:    *intersectionDepth,
+		simplify:             *simplify,
+		reachableFromEntries: *reachableFromEntries,
+		groupBy:              *groupBy,
+		output:               *output,
+		binout:               *binout,
+		codecFlag:            *codecFlag,
+	}
+
+	if *findings {
+		if err := printFindingsReport(context.Background(), input, progress); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *interactive {
+		graph, results, err := loadAndBuild(context.Background(), input, opts, progress)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nerror: %s\n", err)
+			os.Exit(1)
+		}
+
+		runInteractive(graph, results, opts.analyses, opts.intersectionDepth, progress)
+		return
+	}
+
+	if !*watch {
+		if err := renderOnce(context.Background(), input, opts, progress); err != nil {
+			fmt.Fprintf(os.Stderr, "\nerror: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	if input == "-" {
-		f = os.Stdin
-	} else {
-		f, err = os.Open(input)
+		fmt.Fprintln(os.Stderr, "error: -watch needs a real input file, not stdin")
+		os.Exit(1)
+	}
+
+	runWatch(input, opts, progress, *watchDelay, *serve)
+}
+
+// renderOptions bundles the flags that shape a single run of the
+// load/apply/simplify/output pipeline, so -watch can repeat it unchanged.
+type renderOptions struct {
+	legend               bool
+	graphInput           bool
+	analyses             []string
+	intersectionDepth    int
+	simplify             bool
+	reachableFromEntries bool
+	groupBy              string
+	output               string
+	binout               bool
+	codecFlag            string
+}
+
+// renderOnce runs the pipeline exactly once and writes its output.
+func renderOnce(ctx context.Context, input string, opts renderOptions, progress soaap.ProgressReporter) error {
+	graph, _, err := loadAndBuild(ctx, input, opts, progress)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(graph, opts)
+}
+
+// loadAndBuild produces opts' graph from `input`, without writing it
+// anywhere; `results` is only non-nil when the input was raw SOAAP results
+// (so -interactive's `analyses add/remove` can re-run soaap.ApplyAnalysis).
+func loadAndBuild(ctx context.Context, input string, opts renderOptions, progress soaap.ProgressReporter) (soaap.CallGraph, *soaap.Results, error) {
+	var graph soaap.CallGraph
+	var results *soaap.Results
+
+	switch {
+	case opts.legend:
+		graph = soaap.Legend()
+
+	default:
+		f, err := openInput(input)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "error: %s\n", err)
-			return
+			return soaap.CallGraph{}, nil, err
+		}
+		defer f.Close()
+
+		if opts.graphInput || strings.HasSuffix(f.Name(), ".graph") {
+			var codec soaap.GraphCodec
+			codec, err = resolveCodec(opts.codecFlag, f.Name())
+			if err != nil {
+				return soaap.CallGraph{}, nil, err
+			}
+
+			progress.Start(0, fmt.Sprintf("Loading graph data from '%s'...", f.Name()))
+			graph, err = codec.Decode(f)
+			progress.Finish()
+			if err != nil {
+				return soaap.CallGraph{}, nil, err
+			}
+
+		} else {
+			graph, results, err = analyzeResultsFile(ctx, f, opts.analyses, opts.intersectionDepth, progress)
+			if err != nil {
+				return soaap.CallGraph{}, nil, err
+			}
 		}
 	}
 
-	//
-	// Open output file:
-	//
+	nodes, edges, flows := graph.Size()
+	fmt.Printf("Result: %d nodes, %d calls and %d flows\n", nodes, edges, flows)
+
+	if opts.reachableFromEntries {
+		if results == nil {
+			return soaap.CallGraph{}, nil, fmt.Errorf("-reachable-from-entries needs raw SOAAP results, not -graph-input")
+		}
+
+		entries, err := results.EntryPoints(soaap.DefaultEntryPointConfig())
+		if err != nil {
+			return soaap.CallGraph{}, nil, err
+		}
+
+		graph = graph.ReachableFrom(entries)
+		nodes, edges, flows = graph.Size()
+		fmt.Printf("Reachable from %d entry point(s): %d nodes, %d calls and %d flows\n",
+			len(entries), nodes, edges, flows)
+	}
+
+	if opts.simplify {
+		graph = graph.Simplified()
+		nodes, edges, flows = graph.Size()
+		fmt.Printf("Simplified: %d nodes, %d calls and %d flows\n", nodes, edges, flows)
+	}
+
+	return graph, results, nil
+}
+
+func openInput(input string) (*os.File, error) {
+	if input == "-" {
+		return os.Stdin, nil
+	}
+	return soaap.OpenInput(input)
+}
+
+func writeOutput(graph soaap.CallGraph, opts renderOptions) error {
 	var out *os.File
-	if *output == "-" {
+	if opts.output == "-" {
 		out = os.Stdout
 	} else {
-		out, err = os.Create(*output)
+		var err error
+		out, err = os.Create(opts.output)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "error: %s\n", err)
-			return
+			return err
 		}
+		defer out.Close()
 	}
 
 	//
-	// The call/dataflow graph to transform and output.
+	// Output the results: a serialized graph (gob/json/proto) if asked for
+	// explicitly, or implied by the output filename's extension, and a Dot
+	// graph otherwise.
 	//
-	var graph soaap.CallGraph
+	var codec soaap.GraphCodec
+	var err error
 
-	//
-	// Special case: legend of possible node types.
-	//
-	if *legend {
-		graph = soaap.Legend()
+	switch {
+	case opts.codecFlag != "":
+		codec, err = resolveCodec(opts.codecFlag, opts.output)
 
-	} else if strings.HasSuffix(f.Name(), ".graph") {
+	case opts.binout:
+		codec = soaap.GobCodec{}
 
-		// Load binary graph file.
-		report(fmt.Sprintf("Loading binary graph data from '%s'...", f.Name()))
-		graph, err = soaap.LoadGraph(f, report)
+	case hasGraphExtension(opts.output):
+		codec = soaap.CodecForFile(opts.output)
+	}
 
-	} else {
+	if err != nil {
+		return err
+	}
 
-		// Load SOAAP results.
-		report(fmt.Sprintf("Loading SOAAP results from '%s'...", f.Name()))
-		graph, err = analyzeResultsFile(f, analyses)
+	if codec != nil {
+		return codec.Encode(out, graph)
 	}
+	return graph.WriteDot(out, opts.groupBy)
+}
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "\nerror: %s\n", err)
-		os.Exit(1)
+// resolveCodec picks a soaap.GraphCodec by explicit name (gob, json or
+// proto), or by filename extension (see soaap.CodecForFile) when explicit
+// is empty.
+func resolveCodec(explicit, filename string) (soaap.GraphCodec, error) {
+	switch explicit {
+	case "":
+		return soaap.CodecForFile(filename), nil
+	case "gob":
+		return soaap.GobCodec{}, nil
+	case "json":
+		return soaap.JSONCodec{}, nil
+	case "proto":
+		return soaap.ProtoCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown codec %q (want gob, json or proto)", explicit)
 	}
+}
 
-	nodes, edges, flows := graph.Size()
-	fmt.Printf("Result: %d nodes, %d calls and %d flows\n",
-		nodes, edges, flows)
+// hasGraphExtension reports whether name looks like a serialized graph
+// file rather than a Dot file, based on its extension.
+func hasGraphExtension(name string) bool {
+	for _, ext := range []string{".graph", ".json", ".pb", ".protobuf"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
 
-	//
-	// Apply any requested transformations:
-	//
-	if *simplify {
-		graph = graph.Simplified()
-		nodes, edges, flows = graph.Size()
-		fmt.Printf("Simplified: %d nodes, %d calls and %d flows\n",
-			nodes, edges, flows)
+// runWatch re-runs the load/apply/simplify/output pipeline every time
+// `input` changes on disk, debounced by `delay`. Each re-run cancels
+// whatever the previous one hadn't finished yet, so a rapid string of edits
+// doesn't pile up stale work. If `serveAddr` is non-empty and the output
+// looks like Dot/SVG, it also serves the latest rendering over HTTP with a
+// WebSocket that pushes a reload event after each successful re-run.
+func runWatch(input string, opts renderOptions, progress soaap.ProgressReporter, delay time.Duration, serveAddr string) {
+	var hub *soaap.WSHub
+	if serveAddr != "" && (strings.HasSuffix(opts.output, ".dot") || strings.HasSuffix(opts.output, ".svg")) {
+		hub = soaap.NewWSHub()
+		go serveLiveReload(serveAddr, opts, hub)
 	}
 
-	//
-	// Output the results:
-	//
-	if *binout {
-		err = graph.Save(out)
-	} else {
-		err = graph.WriteDot(out, *groupBy)
+	run := func(ctx context.Context) {
+		if err := renderOnce(ctx, input, opts, progress); err != nil {
+			if ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			}
+			return
+		}
+
+		if hub != nil {
+			renderSVGFile(opts.output)
+			hub.Broadcast("reload")
+		}
 	}
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error writing output: %s\n", err)
-		os.Exit(1)
+	// start runs the pipeline in the background so that the loop below can
+	// cancel it as soon as a newer change arrives.
+	start := func(ctx context.Context) chan struct{} {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			run(ctx)
+		}()
+		return done
 	}
+
+	stop := make(chan struct{})
+	events := soaap.WatchFiles([]string{input}, delay, stop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := start(ctx)
+
+	fmt.Fprintf(os.Stderr, "watching '%s' for changes...\n", input)
+
+	for range events {
+		cancel()
+		<-done
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		done = start(ctx)
+	}
+}
+
+// renderSVGFile best-effort renders a Dot output file to SVG (next to it,
+// same basename) with the `dot` tool, the way cmd/soaap-graph's -interactive
+// `web` command does; silently does nothing if `dot` isn't on PATH.
+func renderSVGFile(dotFile string) {
+	svgFile := strings.TrimSuffix(dotFile, ".dot") + ".svg"
+	exec.Command("dot", "-Tsvg", "-o", svgFile, dotFile).Run()
 }
 
-func analyzeResultsFile(f *os.File, analyses []string) (soaap.CallGraph, error) {
+// serveLiveReload serves a small page at "/" that shows the most recently
+// rendered SVG (served at "/graph.svg", if -watch has produced one) and
+// opens a WebSocket back to the hub, reloading the image on every "reload"
+// message.
+func serveLiveReload(addr string, opts renderOptions, hub *soaap.WSHub) {
+	svgFile := strings.TrimSuffix(opts.output, ".dot") + ".svg"
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, liveReloadPage)
+	})
+
+	mux.HandleFunc("/graph.svg", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, svgFile)
+	})
+
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		if err := hub.Upgrade(w, r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	})
+
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		fmt.Fprintf(os.Stderr, "error: -serve: %s\n", err)
+	}
+}
+
+const liveReloadPage = `<!DOCTYPE html>
+<html>
+<head><title>soaap-graph -watch</title></head>
+<body style="margin:0">
+<img id="graph" src="/graph.svg" style="max-width:100%">
+<script>
+  var ws = new WebSocket("ws://" + location.host + "/ws");
+  ws.onmessage = function() {
+    document.getElementById("graph").src = "/graph.svg?" + Date.now();
+  };
+</script>
+</body>
+</html>
+`
+
+func analyzeResultsFile(ctx context.Context, f *os.File, analyses []string, intersectionDepth int,
+	progress soaap.ProgressReporter) (soaap.CallGraph, *soaap.Results, error) {
+
 	//
 	// Combine callgraphs of the requested analyses:
 	//
-	results, err := soaap.LoadResults(f, report)
+	results, err := soaap.LoadResults(ctx, f, progress)
 	if err != nil {
-		return soaap.CallGraph{}, err
+		return soaap.CallGraph{}, nil, err
 	}
 
-	fmt.Println("Initializing empty call graph")
 	graph := soaap.NewCallGraph()
 
 	for _, analysis := range analyses {
 		graph, err = soaap.ApplyAnalysis(
-			analysis, &graph, &results, *intersectionDepth, report)
+			ctx, analysis, &graph, &results, intersectionDepth, progress)
 	}
 
-	return graph, err
+	return graph, &results, err
 }
 
 func printUsage() {
 	fmt.Fprintf(os.Stderr,
-		"Usage:  soaap-graph [options] <input file>\n\n")
+		"Usage:  soaap-graph [options] <input file>\n\n"+
+			"<input file> may also be an http(s):// or s3:// URL.\n\n")
 
 	fmt.Fprintf(os.Stderr, "Options:\n")
 	flag.PrintDefaults()
 }
-
-func report(progress string) {
-	fmt.Println(progress)
-	os.Stdout.Sync()
-}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/CTSRD-SOAAP/gosoaap"
+)
+
+//
+// replState holds -interactive's current filters and the graph they apply
+// to, modeled on cmd/pprof's interactive driver: every command reads from
+// (or extends) this state and renders a *derived* view of the base graph,
+// rather than mutating it in place, so focus/ignore/sandbox compose freely.
+//
+type replState struct {
+	// Only set when the input was raw SOAAP results; nil when it was a
+	// previously-saved graph, in which case `analyses add/remove` can't
+	// recompute anything and reports an error.
+	results *soaap.Results
+
+	analyses []string
+	depth    int
+	progress soaap.ProgressReporter
+
+	base soaap.CallGraph
+
+	focus   []*regexp.Regexp
+	ignore  []*regexp.Regexp
+	sandbox string
+}
+
+//
+// runInteractive drops into a line-based REPL over `base`, exiting on
+// "quit"/"exit" or end of input.
+//
+func runInteractive(base soaap.CallGraph, results *soaap.Results, analyses []string, depth int, progress soaap.ProgressReporter) {
+	state := &replState{
+		results:  results,
+		analyses: append([]string(nil), analyses...),
+		depth:    depth,
+		progress: progress,
+		base:     base,
+	}
+
+	fmt.Println(`soaap-graph interactive mode. Type "help" for commands, "quit" to exit.`)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("(soaap) ")
+		if !scanner.Scan() {
+			fmt.Println()
+			return
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		cmd, args := fields[0], fields[1:]
+		if cmd == "quit" || cmd == "exit" {
+			return
+		}
+
+		if err := state.run(cmd, args); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		}
+	}
+}
+
+func (s *replState) run(cmd string, args []string) error {
+	switch cmd {
+	case "help":
+		printInteractiveHelp()
+		return nil
+
+	case "top":
+		return s.top(args)
+
+	case "list":
+		return s.list(args)
+
+	case "focus":
+		return addPattern(&s.focus, args)
+
+	case "ignore":
+		return addPattern(&s.ignore, args)
+
+	case "sandbox":
+		return s.setSandbox(args)
+
+	case "web":
+		return s.web(args)
+
+	case "flows":
+		return s.flows(args)
+
+	case "simplify":
+		s.base = s.derived().Simplified()
+		s.focus, s.ignore, s.sandbox = nil, nil, ""
+		nodes, edges, flows := s.base.Size()
+		fmt.Printf("simplified: %d nodes, %d calls and %d flows\n", nodes, edges, flows)
+		return nil
+
+	case "save":
+		return s.save(args)
+
+	case "analyses":
+		return s.analysesCmd(args)
+
+	default:
+		return fmt.Errorf("unknown command %q (type \"help\")", cmd)
+	}
+}
+
+// derived applies the REPL's persistent sandbox/focus/ignore filters to the
+// base graph, the way pprof's focus/ignore/hide compose against its
+// baseline profile.
+func (s *replState) derived() soaap.CallGraph {
+	g := s.base
+
+	if s.sandbox != "" {
+		g = g.Filter(keepMatching(g, func(n soaap.GraphNode) bool {
+			return n.Sandbox == s.sandbox
+		}))
+	}
+
+	if len(s.focus) > 0 {
+		g = g.Filter(keepMatching(g, func(n soaap.GraphNode) bool {
+			return nodeMatches(n, s.focus)
+		}))
+	}
+
+	if len(s.ignore) > 0 {
+		g = g.Filter(keepMatching(g, func(n soaap.GraphNode) bool {
+			return !nodeMatches(n, s.ignore)
+		}))
+	}
+
+	return g
+}
+
+func keepMatching(g soaap.CallGraph, want func(soaap.GraphNode) bool) map[string]interface{} {
+	keep := make(map[string]interface{})
+
+	for _, name := range g.NodeNames() {
+		node, _ := g.Node(name)
+		if want(node) {
+			keep[name] = true
+		}
+	}
+
+	return keep
+}
+
+func nodeMatches(n soaap.GraphNode, patterns []*regexp.Regexp) bool {
+	for _, p := range patterns {
+		if p.MatchString(n.Function) || p.MatchString(n.Name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// top ranks nodes by degree (in+out calls and flows), or, with "top flows
+// [N]", by the number of data/privilege flows alone, and prints the top N
+// (default 10).
+func (s *replState) top(args []string) error {
+	byFlows := false
+	if len(args) > 0 && args[0] == "flows" {
+		byFlows = true
+		args = args[1:]
+	}
+
+	n := 10
+	if len(args) > 0 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid count %q", args[0])
+		}
+		n = v
+	}
+
+	g := s.derived()
+	names := g.NodeNames()
+
+	type ranked struct {
+		node  soaap.GraphNode
+		score int
+	}
+
+	all := make([]ranked, 0, len(names))
+	for _, name := range names {
+		node, _ := g.Node(name)
+
+		score := 0
+		if byFlows {
+			score = len(node.FlowsIn) + len(node.FlowsOut)
+		} else {
+			in, out := g.Degree(name)
+			score = in + out
+		}
+
+		all = append(all, ranked{node, score})
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].score != all[j].score {
+			return all[i].score > all[j].score
+		}
+		return all[i].node.Name < all[j].node.Name
+	})
+
+	if n > len(all) {
+		n = len(all)
+	}
+
+	for i, r := range all[:n] {
+		fmt.Printf("%4d  %4d  %s%s\n", i+1, r.score, r.node.Function, annotations(r.node))
+	}
+
+	return nil
+}
+
+// list prints every node whose function or name matches `regex`, annotated
+// with its sandbox and any known CVEs, the way WriteDot labels it.
+func (s *replState) list(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: list <regex>")
+	}
+
+	pattern, err := regexp.Compile(args[0])
+	if err != nil {
+		return err
+	}
+
+	g := s.derived()
+	names := g.NodeNames()
+	sort.Strings(names)
+
+	for _, name := range names {
+		node, _ := g.Node(name)
+		if pattern.MatchString(node.Function) || pattern.MatchString(node.Name) {
+			fmt.Printf("%s%s\n", node.Function, annotations(node))
+		}
+	}
+
+	return nil
+}
+
+func annotations(node soaap.GraphNode) string {
+	var b strings.Builder
+
+	if len(node.CVE) > 0 {
+		b.WriteString(" " + node.CVE.TransformEach("[[%s]]").Join(" "))
+	}
+	if len(node.Owners) > 0 {
+		b.WriteString(" (private: " + node.Owners.Join(", ") + ")")
+	}
+	if node.Sandbox != "" {
+		b.WriteString(" <<" + node.Sandbox + ">>")
+	}
+
+	return b.String()
+}
+
+func addPattern(list *[]*regexp.Regexp, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: focus|ignore <regex>")
+	}
+
+	p, err := regexp.Compile(args[0])
+	if err != nil {
+		return err
+	}
+
+	*list = append(*list, p)
+	return nil
+}
+
+func (s *replState) setSandbox(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: sandbox <name>")
+	}
+
+	s.sandbox = args[0]
+	return nil
+}
+
+// flows prints the data/privilege flows from nodes matching `src` to nodes
+// matching `dst`.
+func (s *replState) flows(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: flows <src regex> <dst regex>")
+	}
+
+	src, err := regexp.Compile(args[0])
+	if err != nil {
+		return err
+	}
+
+	dst, err := regexp.Compile(args[1])
+	if err != nil {
+		return err
+	}
+
+	g := s.derived()
+	found := false
+
+	for _, name := range g.NodeNames() {
+		node, _ := g.Node(name)
+		if !src.MatchString(node.Function) && !src.MatchString(node.Name) {
+			continue
+		}
+
+		for _, flow := range node.FlowsOut {
+			callee, ok := g.Node(flow.Callee)
+			if !ok || (!dst.MatchString(callee.Function) && !dst.MatchString(callee.Name)) {
+				continue
+			}
+
+			fmt.Printf("%s -> %s%s\n", node.Function, callee.Function, annotations(callee))
+			found = true
+		}
+	}
+
+	if !found {
+		fmt.Println("no matching flows.")
+	}
+
+	return nil
+}
+
+// web writes the current derived subgraph as Dot, renders it to SVG with
+// `dot` if available, and tries to open it in a browser. Both of those are
+// best-effort: if `dot` or a browser opener isn't on PATH, the Dot file is
+// still left behind for the user to render by hand.
+func (s *replState) web(args []string) error {
+	file := "soaap-web.dot"
+	if len(args) > 0 {
+		file = args[0]
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := s.derived().WriteDot(f, ""); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %s\n", file)
+
+	if svg, err := renderSVG(file); err == nil {
+		fmt.Printf("wrote %s\n", svg)
+		openInBrowser(svg)
+	}
+
+	return nil
+}
+
+func renderSVG(dotFile string) (string, error) {
+	svgFile := strings.TrimSuffix(dotFile, filepath.Ext(dotFile)) + ".svg"
+
+	if err := exec.Command("dot", "-Tsvg", "-o", svgFile, dotFile).Run(); err != nil {
+		return "", err
+	}
+
+	return svgFile, nil
+}
+
+func openInBrowser(path string) {
+	opener := "xdg-open"
+	switch runtime.GOOS {
+	case "darwin":
+		opener = "open"
+	case "windows":
+		opener = "start"
+	}
+
+	// Best-effort: there's no browser to open in headless environments,
+	// and that's not worth failing the command over.
+	_ = exec.Command(opener, path).Start()
+}
+
+func (s *replState) save(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: save <file>")
+	}
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	codec, err := resolveCodec("", args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := codec.Encode(f, s.derived()); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %s\n", args[0])
+	return nil
+}
+
+// analysesCmd adds or removes an analysis from the active set and rebuilds
+// the base graph from scratch via soaap.ApplyAnalysis, the same sequence
+// analyzeResultsFile uses at startup.
+func (s *replState) analysesCmd(args []string) error {
+	if s.results == nil {
+		return fmt.Errorf("analyses require SOAAP results as input, not a saved graph")
+	}
+
+	if len(args) != 2 || (args[0] != "add" && args[0] != "remove") {
+		return fmt.Errorf("usage: analyses add|remove <name>")
+	}
+
+	name := args[1]
+
+	switch args[0] {
+	case "add":
+		s.analyses = append(s.analyses, name)
+
+	case "remove":
+		kept := s.analyses[:0]
+		for _, a := range s.analyses {
+			if a != name {
+				kept = append(kept, a)
+			}
+		}
+		s.analyses = kept
+	}
+
+	graph := soaap.NewCallGraph()
+	var err error
+
+	for _, a := range s.analyses {
+		graph, err = soaap.ApplyAnalysis(context.Background(), a, &graph, s.results, s.depth, s.progress)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.base = graph
+	s.focus, s.ignore, s.sandbox = nil, nil, ""
+
+	nodes, edges, flows := s.base.Size()
+	fmt.Printf("rebuilt: %d nodes, %d calls and %d flows (analyses: %s)\n",
+		nodes, edges, flows, strings.Join(s.analyses, ", "))
+
+	return nil
+}
+
+func printInteractiveHelp() {
+	fmt.Print(`Commands:
+  top [N]               nodes ranked by in+out degree (default 10)
+  top flows [N]         nodes ranked by number of data/privilege flows
+  list <regex>          matching nodes, annotated with sandbox/CVE/owners
+  focus <regex>         keep only nodes matching regex (persists)
+  ignore <regex>        drop nodes matching regex (persists)
+  sandbox <name>        keep only nodes in the given sandbox (persists)
+  flows <src> <dst>     data/privilege flows from nodes matching src to dst
+  simplify              collapse call chains in the current view
+  web [file]            write the current view as Dot (and SVG/browser, if available)
+  save <file>           write the current view via the codec implied by its extension
+  analyses add <name>   add an analysis and rebuild the graph
+  analyses remove <name> remove an analysis and rebuild the graph
+  help                  this message
+  quit / exit           leave interactive mode
+`)
+}
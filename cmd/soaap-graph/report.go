@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CTSRD-SOAAP/gosoaap"
+)
+
+// findingRankNames mirrors soaap.Rank* for human-readable -findings output.
+var findingRankNames = map[int]string{
+	soaap.RankUnreachable: "unreachable",
+	soaap.RankLibraryOnly: "library-only",
+	soaap.RankTraced:      "traced",
+	soaap.RankReachable:   "reachable",
+}
+
+// printFindingsReport loads `input` as raw SOAAP results and prints its
+// ranked soaap.Findings, highest rank first, followed by the Vulns that
+// Unreachable singles out as having no trace at all.
+func printFindingsReport(ctx context.Context, input string, progress soaap.ProgressReporter) error {
+	f, err := openInput(input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	results, err := soaap.LoadResults(ctx, f, progress)
+	if err != nil {
+		return err
+	}
+
+	findings, err := results.Findings()
+	if err != nil {
+		return err
+	}
+
+	for _, finding := range findings {
+		fmt.Printf("[%s] %s (%d vuln(s), %d access(es), %d path(s))\n",
+			findingRankNames[finding.Rank], finding.Key,
+			len(finding.Vulns), len(finding.PrivAccess), len(finding.Paths))
+	}
+
+	unreachable, err := results.Unreachable()
+	if err != nil {
+		return err
+	}
+
+	if len(unreachable) > 0 {
+		fmt.Printf("\n%d vuln(s) with no reachable trace at all:\n", len(unreachable))
+		for _, v := range unreachable {
+			fmt.Printf("  %s: %s\n", v.Type, v.CallSite.Function)
+		}
+	}
+
+	return nil
+}
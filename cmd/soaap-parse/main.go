@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -13,6 +14,12 @@ func main() {
 	// Command-line arguments:
 	//
 	output := flag.String("output", "-", "output GraphViz file")
+	quiet := flag.Bool("quiet", false, "suppress progress reporting")
+	osvURL := flag.String("enrich-osv-url", "",
+		"fetch OSV entries for every Vuln's CVE IDs from this OSV-schema API's vuln-by-ID endpoint"+
+			" (e.g. https://api.osv.dev/v1/vulns) and attach them before saving")
+	osvDir := flag.String("enrich-osv-dir", "",
+		"fetch OSV entries from this local mirror directory of <id>.json files instead of -enrich-osv-url")
 	flag.Parse()
 
 	if len(flag.Args()) != 1 {
@@ -45,12 +52,23 @@ func main() {
 	//
 	// Parse SOAAP results:
 	//
-	results, err := soaap.LoadResults(f, reportProgress)
+	progress := soaap.NewProgressReporter(os.Stderr, *quiet, func(s string) { fmt.Println(s) })
+	results, err := soaap.LoadResults(context.Background(), f, progress)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %s\n", err)
 		return
 	}
 
+	if client := osvClient(*osvURL, *osvDir); client != nil {
+		fmt.Print("Enriching with OSV data...")
+		if err := results.EnrichOSV(context.Background(), client); err != nil {
+			fmt.Println()
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			return
+		}
+		fmt.Println(" done.")
+	}
+
 	// Encode it as a gob of data:
 	//
 	fmt.Print("Encoding...")
@@ -60,6 +78,20 @@ func main() {
 	outfile.Sync()
 }
 
+// osvClient picks an soaap.OSVClient from -enrich-osv-dir/-enrich-osv-url,
+// preferring the local mirror when both are given; returns nil if neither
+// was set, meaning no enrichment was requested.
+func osvClient(url, dir string) soaap.OSVClient {
+	switch {
+	case dir != "":
+		return &soaap.OSVDirClient{Dir: dir}
+	case url != "":
+		return &soaap.OSVHTTPClient{BaseURL: url}
+	default:
+		return nil
+	}
+}
+
 func printUsage() {
 	fmt.Fprintf(os.Stderr,
 		"Usage:  soaap-graph [options] <input file>\n\n")
@@ -67,7 +99,3 @@ func printUsage() {
 	fmt.Fprintf(os.Stderr, "Options:\n")
 	flag.PrintDefaults()
 }
-
-func reportProgress(message string) {
-	fmt.Println(message)
-}
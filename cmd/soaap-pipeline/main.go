@@ -0,0 +1,138 @@
+// soaap-pipeline runs a declarative pipeline of graph-building stages
+// described in a YAML config, in place of shell-piping several invocations
+// of soaap-graph/soaap-combine with hand-tracked intermediate .graph files.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/CTSRD-SOAAP/gosoaap"
+)
+
+func main() {
+	config := flag.String("config", "", "pipeline config file (required)")
+	quiet := flag.Bool("quiet", false, "suppress progress reporting")
+	watch := flag.Bool("watch", false,
+		"re-run the pipeline whenever one of its local inputs changes")
+	watchDelay := flag.Duration("watch-delay", 250*time.Millisecond, "debounce interval for -watch")
+	flag.Parse()
+
+	if *config == "" {
+		printUsage()
+		os.Exit(1)
+	}
+
+	progress := soaap.NewProgressReporter(os.Stderr, *quiet, func(s string) { fmt.Println(s) })
+
+	if !*watch {
+		if err := runOnce(context.Background(), *config, progress); err != nil {
+			die("%s", err)
+		}
+		return
+	}
+
+	runWatch(*config, progress, *watchDelay)
+}
+
+func runOnce(ctx context.Context, configFile string, progress soaap.ProgressReporter) error {
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		return err
+	}
+
+	graphs, err := soaap.RunPipeline(ctx, cfg, progress)
+	if err != nil {
+		return err
+	}
+
+	for _, stage := range cfg.Stages {
+		graph := graphs[stage.Name]
+		nodes, edges, flows := graph.Size()
+		fmt.Printf("%s: %d nodes, %d calls and %d flows\n", stage.Name, nodes, edges, flows)
+	}
+
+	return nil
+}
+
+// runWatch re-runs the pipeline every time one of its stages' local (i.e.
+// not http(s):// or s3://) inputs changes on disk, debounced by `delay`,
+// cancelling whatever the previous run hadn't finished yet.
+func runWatch(configFile string, progress soaap.ProgressReporter, delay time.Duration) {
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		die("%s", err)
+	}
+
+	var paths []string
+	for _, stage := range cfg.Stages {
+		if stage.Input != nil && !soaap.IsRemote(stage.Input.Path) {
+			paths = append(paths, stage.Input.Path)
+		}
+	}
+
+	run := func(ctx context.Context) {
+		if err := runOnce(ctx, configFile, progress); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		}
+	}
+
+	// start runs the pipeline in the background so that the loop below can
+	// cancel it as soon as a newer change arrives.
+	start := func(ctx context.Context) chan struct{} {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			run(ctx)
+		}()
+		return done
+	}
+
+	stop := make(chan struct{})
+	events := soaap.WatchFiles(paths, delay, stop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := start(ctx)
+
+	fmt.Fprintf(os.Stderr, "watching %d input(s) for changes...\n", len(paths))
+
+	for range events {
+		cancel()
+		<-done
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		done = start(ctx)
+	}
+}
+
+func loadConfig(configFile string) (soaap.PipelineConfig, error) {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return soaap.PipelineConfig{}, fmt.Errorf("error reading %s: %w", configFile, err)
+	}
+
+	cfg, err := soaap.ParsePipelineConfig(data)
+	if err != nil {
+		return soaap.PipelineConfig{}, fmt.Errorf("error parsing %s: %w", configFile, err)
+	}
+
+	return cfg, nil
+}
+
+func die(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `Usage: soaap-pipeline -config <file>
+
+Runs the DAG of stages described in <file> (see PipelineConfig in the
+soaap package for the schema) and writes out each stage's "output" block,
+if any.`)
+	flag.PrintDefaults()
+}
@@ -0,0 +1,298 @@
+// soaap-query answers digraph-style questions about a saved call graph, in
+// the spirit of golang.org/x/tools/cmd/digraph but walking soaap.CallGraph's
+// calls and data flows together.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/CTSRD-SOAAP/gosoaap"
+)
+
+func main() {
+	dotOut := flag.Bool("dot", false, "write a Dot subgraph instead of a node list")
+	codecFlag := flag.String("codec", "",
+		"graph codec to use for input: gob, json or proto"+
+			" (default: inferred from the file's extension)")
+
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 2 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	graphFile, command, rest := args[0], args[1], args[2:]
+
+	graph, err := loadGraph(graphFile, *codecFlag)
+	if err != nil {
+		die("error loading graph from '%s': %s", graphFile, err)
+	}
+
+	if err := runCommand(command, rest, graph, *dotOut); err != nil {
+		die("%s", err)
+	}
+}
+
+func runCommand(command string, args []string, graph soaap.CallGraph, dotOut bool) error {
+	switch command {
+	case "nodes":
+		return printNodes(graph, allNodeNames(graph), dotOut)
+
+	case "degree":
+		for _, n := range nodeArgs(args) {
+			in, out := graph.Degree(n)
+			fmt.Printf("%d\t%d\t%s\n", in, out, n)
+		}
+		return nil
+
+	case "preds":
+		keep := make(map[string]bool)
+		for _, n := range nodeArgs(args) {
+			for p := range graph.Preds(n) {
+				keep[p] = true
+			}
+		}
+		return printNodes(graph, keys(keep), dotOut)
+
+	case "succs":
+		keep := make(map[string]bool)
+		for _, n := range nodeArgs(args) {
+			for s := range graph.Succs(n) {
+				keep[s] = true
+			}
+		}
+		return printNodes(graph, keys(keep), dotOut)
+
+	case "forward":
+		seeds := toSet(nodeArgs(args))
+		return printNodes(graph, setKeys(graph.Forward(seeds)), dotOut)
+
+	case "reverse":
+		seeds := toSet(nodeArgs(args))
+		return printNodes(graph, setKeys(graph.Reverse(seeds)), dotOut)
+
+	case "somepath":
+		from, to, err := pairArgs(args)
+		if err != nil {
+			return err
+		}
+		path := graph.SomePath(from, to)
+		if path == nil {
+			return fmt.Errorf("no path from %q to %q", from, to)
+		}
+		return printNodes(graph, path, dotOut)
+
+	case "allpaths":
+		from, to, err := pairArgs(args)
+		if err != nil {
+			return err
+		}
+		return writeGraph(graph.AllPaths(from, to), dotOut)
+
+	case "pathsto":
+		n, err := singleArg(args)
+		if err != nil {
+			return err
+		}
+
+		paths := graph.PathsTo(soaap.CallSite{Function: n})
+		if len(paths) == 0 {
+			return fmt.Errorf("no path to %q", n)
+		}
+
+		if dotOut {
+			return fmt.Errorf("pathsto doesn't support -dot")
+		}
+
+		for _, path := range paths {
+			names := make([]string, len(path))
+			for i, cs := range path {
+				names[i] = cs.Function
+			}
+			fmt.Println(strings.Join(names, " -> "))
+		}
+		return nil
+
+	case "sccs":
+		for _, scc := range graph.StronglyConnectedComponents() {
+			sort.Strings(scc)
+			fmt.Println(strings.Join(scc, ", "))
+		}
+		return nil
+
+	case "scc":
+		n, err := singleArg(args)
+		if err != nil {
+			return err
+		}
+		scc := graph.SCCOf(n)
+		if scc == nil {
+			return fmt.Errorf("no such node: %q", n)
+		}
+		return printNodes(graph, scc, dotOut)
+
+	case "focus":
+		n, err := singleArg(args)
+		if err != nil {
+			return err
+		}
+		return writeGraph(graph.Focus(n), dotOut)
+
+	case "transpose":
+		return writeGraph(graph.Transpose(), dotOut)
+
+	default:
+		return fmt.Errorf("unknown command: %q", command)
+	}
+}
+
+// printNodes prints `names` (one per line), or, with -dot, the Dot
+// rendering of the subgraph cg.Filter'd down to just those nodes.
+func printNodes(graph soaap.CallGraph, names []string, dotOut bool) error {
+	if dotOut {
+		return writeGraph(graph.Filter(toSet(names)), true)
+	}
+
+	for _, n := range names {
+		fmt.Println(n)
+	}
+
+	return nil
+}
+
+func writeGraph(graph soaap.CallGraph, dotOut bool) error {
+	if !dotOut {
+		return printNodes(graph, allNodeNames(graph), false)
+	}
+
+	return graph.WriteDot(os.Stdout, "")
+}
+
+// nodeArgs returns `args` if non-empty, otherwise reads newline-separated
+// node IDs from stdin, the way digraph does when no arguments are given.
+func nodeArgs(args []string) []string {
+	if len(args) > 0 {
+		return args
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			names = append(names, line)
+		}
+	}
+
+	return names
+}
+
+func pairArgs(args []string) (string, string, error) {
+	if len(args) != 2 {
+		return "", "", fmt.Errorf("expected exactly 2 node IDs, got %d", len(args))
+	}
+	return args[0], args[1], nil
+}
+
+func singleArg(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("expected exactly 1 node ID, got %d", len(args))
+	}
+	return args[0], nil
+}
+
+func allNodeNames(graph soaap.CallGraph) []string {
+	names := graph.NodeNames()
+	sort.Strings(names)
+	return names
+}
+
+func keys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func setKeys(m map[string]interface{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func toSet(names []string) map[string]interface{} {
+	s := make(map[string]interface{}, len(names))
+	for _, n := range names {
+		s[n] = true
+	}
+	return s
+}
+
+func loadGraph(filename, codecFlag string) (soaap.CallGraph, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return soaap.CallGraph{}, err
+	}
+	defer f.Close()
+
+	codec, err := resolveCodec(codecFlag, filename)
+	if err != nil {
+		return soaap.CallGraph{}, err
+	}
+
+	return codec.Decode(f)
+}
+
+func resolveCodec(explicit, filename string) (soaap.GraphCodec, error) {
+	switch explicit {
+	case "":
+		return soaap.CodecForFile(filename), nil
+	case "gob":
+		return soaap.GobCodec{}, nil
+	case "json":
+		return soaap.JSONCodec{}, nil
+	case "proto":
+		return soaap.ProtoCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown codec %q (want gob, json or proto)", explicit)
+	}
+}
+
+func die(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `Usage: soaap-query [-dot] [-codec gob|json|proto] <graph file> <command> [args...]
+
+Commands:
+  nodes                 list every node
+  degree N...           print "in\tout\tname" for each node
+  preds N...            immediate predecessors of the given nodes
+  succs N...            immediate successors of the given nodes
+  forward N...          nodes transitively reachable from the given nodes
+  reverse N...          nodes that can transitively reach the given nodes
+  somepath A B          some path from A to B
+  allpaths A B          the subgraph of every node on some path from A to B
+  pathsto N             every root-to-N path, by function name (see CallGraph.PathsTo)
+  sccs                  print each strongly connected component, one per line
+  scc N                 print the strongly connected component containing N
+  focus N               the subgraph reachable to or from N
+  transpose             the graph with every edge reversed
+
+If N... is omitted, node IDs are read one per line from stdin.`)
+	flag.PrintDefaults()
+}
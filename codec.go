@@ -0,0 +1,333 @@
+package soaap
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/CTSRD-SOAAP/gosoaap/soaappb"
+)
+
+//
+// GraphCodec encodes and decodes a CallGraph to and from some on-disk
+// representation.
+//
+// Implementations must reconstitute each node's CallsIn/CallsOut/FlowsIn/
+// FlowsOut from the encoded calls/flows on Decode, the way LoadGraph always
+// has, so that an encoded graph only needs to carry each edge once rather
+// than duplicating it inside every node it touches.
+//
+type GraphCodec interface {
+	Encode(w io.Writer, cg CallGraph) error
+	Decode(r io.Reader) (CallGraph, error)
+}
+
+//
+// CodecForFile picks the GraphCodec matching name's extension: ".json" for
+// JSONCodec, ".pb" or ".protobuf" for ProtoCodec, and anything else
+// (including the historical ".graph") for GobCodec.
+//
+func CodecForFile(name string) GraphCodec {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return JSONCodec{}
+	case ".pb", ".protobuf":
+		return ProtoCodec{}
+	default:
+		return GobCodec{}
+	}
+}
+
+//
+// GobCodec is the binary encoding Save/LoadGraph have always used: a
+// sequence of gob-encoded values. It's compact, but Go-specific and tied to
+// gob's own wire format, so it can't be read by anything outside this
+// module (see JSONCodec, ProtoCodec).
+//
+type GobCodec struct{}
+
+func (GobCodec) Encode(w io.Writer, cg CallGraph) error {
+	return gobEncode(w, cg)
+}
+
+func (GobCodec) Decode(r io.Reader) (CallGraph, error) {
+	return gobDecode(r)
+}
+
+//
+// JSONCodec encodes a CallGraph as a single JSON object:
+//
+//	{
+//	  "nodes":  { "<name>": { "function": ..., "kind": ..., "cve": [...], ... } },
+//	  "calls":  [ { "caller": ..., "callee": ..., "callsite": {...}, "sandbox": ..., "weight": ... } ],
+//	  "flows":  [ same shape as "calls" ],
+//	  "roots":  [ "<name>", ... ],
+//	  "leaves": [ "<name>", ... ]
+//	}
+//
+// This schema is stable: new optional fields may be added to a node or
+// edge, but existing field names and meanings won't change, so that other
+// tooling (web viewers, notebooks, govulncheck-style pipelines) can consume
+// it without linking against this module.
+//
+type JSONCodec struct{}
+
+// jsonNode is a node's JSON representation, keyed by name in jsonDoc.Nodes.
+// CallsIn/CallsOut/FlowsIn/FlowsOut aren't included: they're reconstituted
+// from jsonDoc.Calls/Flows on decode, the same as GobCodec.
+type jsonNode struct {
+	Kind     NodeKind `json:"kind"`
+	Function string   `json:"function"`
+	Library  string   `json:"library,omitempty"`
+	Sandbox  string   `json:"sandbox,omitempty"`
+	CVE      []string `json:"cve,omitempty"`
+	Owners   []string `json:"owners,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Members  []string `json:"members,omitempty"`
+}
+
+type jsonEdge struct {
+	Caller   string         `json:"caller"`
+	Callee   string         `json:"callee"`
+	CallSite SourceLocation `json:"callsite"`
+	Sandbox  string         `json:"sandbox,omitempty"`
+	Weight   int            `json:"weight"`
+}
+
+type jsonDoc struct {
+	Nodes  map[string]jsonNode `json:"nodes"`
+	Calls  []jsonEdge          `json:"calls"`
+	Flows  []jsonEdge          `json:"flows"`
+	Roots  []string            `json:"roots"`
+	Leaves []string            `json:"leaves"`
+}
+
+func (JSONCodec) Encode(w io.Writer, cg CallGraph) error {
+	doc := jsonDoc{
+		Nodes:  make(map[string]jsonNode, len(cg.nodes)),
+		Calls:  edgesToJSON(cg.calls),
+		Flows:  edgesToJSON(cg.flows),
+		Roots:  cg.roots.Values(),
+		Leaves: cg.leaves.Values(),
+	}
+	sort.Strings(doc.Roots)
+	sort.Strings(doc.Leaves)
+
+	for name, n := range cg.nodes {
+		doc.Nodes[name] = jsonNode{
+			Kind:     n.Kind,
+			Function: n.Function,
+			Library:  n.Library,
+			Sandbox:  n.Sandbox,
+			CVE:      sortedValues(n.CVE),
+			Owners:   sortedValues(n.Owners),
+			Tags:     sortedValues(n.Tags),
+			Members:  n.Members,
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func (JSONCodec) Decode(r io.Reader) (CallGraph, error) {
+	var doc jsonDoc
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return CallGraph{}, err
+	}
+
+	cg := NewCallGraph()
+
+	for name, n := range doc.Nodes {
+		node := GraphNode{
+			Name:     name,
+			Kind:     n.Kind,
+			Function: n.Function,
+			Library:  n.Library,
+			Sandbox:  n.Sandbox,
+			CVE:      toStrset(n.CVE),
+			Owners:   toStrset(n.Owners),
+			Tags:     toStrset(n.Tags),
+			CallsIn:  make([]Call, 0),
+			CallsOut: make([]Call, 0),
+			FlowsIn:  make([]Call, 0),
+			FlowsOut: make([]Call, 0),
+			Members:  n.Members,
+		}
+		cg.AddNode(node)
+	}
+
+	for _, e := range doc.Calls {
+		cg.AddCalls(jsonToCall(e), e.Weight)
+	}
+	for _, e := range doc.Flows {
+		cg.AddFlows(jsonToCall(e), e.Weight)
+	}
+
+	return cg, nil
+}
+
+func edgesToJSON(edges map[Call]int) []jsonEdge {
+	out := make([]jsonEdge, 0, len(edges))
+
+	for call, weight := range edges {
+		out = append(out, jsonEdge{
+			Caller:   call.Caller,
+			Callee:   call.Callee,
+			CallSite: call.CallSite,
+			Sandbox:  call.Sandbox,
+			Weight:   weight,
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Caller != out[j].Caller {
+			return out[i].Caller < out[j].Caller
+		}
+		return out[i].Callee < out[j].Callee
+	})
+
+	return out
+}
+
+func jsonToCall(e jsonEdge) Call {
+	return Call{
+		Caller:   e.Caller,
+		Callee:   e.Callee,
+		CallSite: e.CallSite,
+		Sandbox:  e.Sandbox,
+	}
+}
+
+func sortedValues(s strset) []string {
+	v := s.Values()
+	sort.Strings(v)
+	return v
+}
+
+func toStrset(values []string) strset {
+	s := make(strset)
+	for _, v := range values {
+		s.Add(v)
+	}
+	return s
+}
+
+//
+// ProtoCodec encodes a CallGraph using the Graph message declared in
+// soaappb/graph.proto: the same information as JSONCodec, packed into the
+// binary protobuf wire format instead of text. The message is written as is,
+// with no overall length prefix.
+//
+type ProtoCodec struct{}
+
+func (ProtoCodec) Encode(w io.Writer, cg CallGraph) error {
+	g := soaappb.Graph{
+		Calls:  edgesToProto(cg.calls),
+		Flows:  edgesToProto(cg.flows),
+		Roots:  cg.roots.Values(),
+		Leaves: cg.leaves.Values(),
+	}
+	sort.Strings(g.Roots)
+	sort.Strings(g.Leaves)
+
+	for name, n := range cg.nodes {
+		g.Nodes = append(g.Nodes, soaappb.Node{
+			Name:     name,
+			Function: n.Function,
+			Library:  n.Library,
+			Sandbox:  n.Sandbox,
+			Kind:     int32(n.Kind),
+			CVE:      sortedValues(n.CVE),
+			Owners:   sortedValues(n.Owners),
+			Tags:     sortedValues(n.Tags),
+			Members:  n.Members,
+		})
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].Name < g.Nodes[j].Name })
+
+	_, err := w.Write(g.Marshal())
+	return err
+}
+
+func (ProtoCodec) Decode(r io.Reader) (CallGraph, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return CallGraph{}, err
+	}
+
+	g, err := soaappb.Unmarshal(data)
+	if err != nil {
+		return CallGraph{}, err
+	}
+
+	cg := NewCallGraph()
+
+	for _, n := range g.Nodes {
+		cg.AddNode(GraphNode{
+			Name:     n.Name,
+			Kind:     NodeKind(n.Kind),
+			Function: n.Function,
+			Library:  n.Library,
+			Sandbox:  n.Sandbox,
+			CVE:      toStrset(n.CVE),
+			Owners:   toStrset(n.Owners),
+			Tags:     toStrset(n.Tags),
+			CallsIn:  make([]Call, 0),
+			CallsOut: make([]Call, 0),
+			FlowsIn:  make([]Call, 0),
+			FlowsOut: make([]Call, 0),
+			Members:  n.Members,
+		})
+	}
+
+	for _, e := range g.Calls {
+		cg.AddCalls(protoToCall(e), int(e.Weight))
+	}
+	for _, e := range g.Flows {
+		cg.AddFlows(protoToCall(e), int(e.Weight))
+	}
+
+	return cg, nil
+}
+
+func edgesToProto(edges map[Call]int) []soaappb.Edge {
+	out := make([]soaappb.Edge, 0, len(edges))
+
+	for call, weight := range edges {
+		out = append(out, soaappb.Edge{
+			Caller:  call.Caller,
+			Callee:  call.Callee,
+			File:    call.CallSite.File,
+			Line:    int32(call.CallSite.Line),
+			Library: call.CallSite.Library,
+			Sandbox: call.Sandbox,
+			Weight:  int32(weight),
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Caller != out[j].Caller {
+			return out[i].Caller < out[j].Caller
+		}
+		return out[i].Callee < out[j].Callee
+	})
+
+	return out
+}
+
+func protoToCall(e soaappb.Edge) Call {
+	return Call{
+		Caller: e.Caller,
+		Callee: e.Callee,
+		CallSite: SourceLocation{
+			File:    e.File,
+			Line:    int(e.Line),
+			Library: e.Library,
+		},
+		Sandbox: e.Sandbox,
+	}
+}
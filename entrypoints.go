@@ -0,0 +1,205 @@
+package soaap
+
+import (
+	"fmt"
+	"strings"
+)
+
+//
+// Heuristics used to decide whether a trace's root CallSite is a genuine
+// program entry point, analogous to the heuristics SSA-based tools use to
+// seed a call graph from `main`/`init` and other user code.
+//
+type EntryPointConfig struct {
+	// Function names (after stripping any "(...)" parameter list) that are
+	// always considered entry points, e.g. "main".
+	MainNames []string
+
+	// Function name prefixes that mark package/library initializers,
+	// e.g. "init".
+	InitPrefixes []string
+
+	// Packages/namespaces (matched as a "Pkg::" prefix of the function
+	// name) whose functions, including nested names under that prefix,
+	// are considered entry points even without being named "main".
+	UserPackages []string
+}
+
+//
+// DefaultEntryPointConfig recognizes "main" (top-level or as the last
+// "::"-separated component of a name) and any function whose name starts
+// with "init"; it names no UserPackages.
+//
+func DefaultEntryPointConfig() EntryPointConfig {
+	return EntryPointConfig{
+		MainNames:    []string{"main"},
+		InitPrefixes: []string{"init"},
+	}
+}
+
+//
+// Matches reports whether cs looks like an entry point under cfg's
+// heuristics.
+//
+func (cfg EntryPointConfig) Matches(cs CallSite) bool {
+	function := strings.Split(cs.Function, "(")[0]
+
+	for _, name := range cfg.MainNames {
+		if function == name || strings.HasSuffix(function, "::"+name) {
+			return true
+		}
+	}
+
+	for _, prefix := range cfg.InitPrefixes {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+
+	for _, pkg := range cfg.UserPackages {
+		if strings.HasPrefix(function, pkg+"::") {
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// EntryPoints walks every trace in r, following `Next` references to each
+// trace's root CallSite (the deepest frame, reached last), and returns the
+// deduplicated set of roots that look like entry points under cfg.
+//
+func (r Results) EntryPoints(cfg EntryPointConfig) ([]CallSite, error) {
+	seen := make(strset)
+	entries := make([]CallSite, 0)
+
+	for i := range r.Traces {
+		root, err := terminalCallSite(r.Traces, i)
+		if err != nil {
+			return nil, fmt.Errorf("trace %d: %w", i, err)
+		}
+
+		if root == nil || !cfg.Matches(*root) {
+			continue
+		}
+
+		key := root.String()
+		if seen.Contains(key) {
+			continue
+		}
+		seen.Add(key)
+
+		entries = append(entries, *root)
+	}
+
+	return entries, nil
+}
+
+// terminalCallSite returns the last CallSite reached by following the
+// `Next` chain from trace `idx`, or nil if that trace (and everything it
+// chains to) is empty.
+func terminalCallSite(traces []CallTrace, idx int) (*CallSite, error) {
+	var last CallSite
+	found := false
+
+	err := traces[idx].ForeachN(traces, DefaultMaxTraceDepth, func(cs CallSite) {
+		last = cs
+		found = true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	return &last, nil
+}
+
+//
+// ReachableFrom returns the subgraph of cg reachable (by calls or data
+// flows) from any node whose Function matches one of `entries`.
+//
+func (cg CallGraph) ReachableFrom(entries []CallSite) CallGraph {
+	seeds := make(strset)
+
+	for _, cs := range entries {
+		for name, node := range cg.nodes {
+			if node.Function == cs.Function {
+				seeds.Add(name)
+			}
+		}
+	}
+
+	// expandToFixedPoint rather than CollectNodes(..., -1): a real entry
+	// point is routinely part of a cycle (direct or mutual recursion), and
+	// CollectNodes' unbounded recursion never terminates on one.
+	keep := cg.expandToFixedPoint(seeds, GraphNode.AllOutputs)
+
+	result := NewCallGraph()
+
+	for id := range keep {
+		result.AddNode(cg.nodes[id])
+	}
+
+	for call, weight := range cg.calls {
+		if keep.Contains(call.Caller) && keep.Contains(call.Callee) {
+			result.AddCalls(call, weight)
+		}
+	}
+
+	for flow, weight := range cg.flows {
+		if keep.Contains(flow.Caller) && keep.Contains(flow.Callee) {
+			result.AddFlows(flow, weight)
+		}
+	}
+
+	return result
+}
+
+//
+// PathsTo returns every simple root-to-sink path (in root-first order)
+// through cg that ends at a node whose Function matches sink.
+//
+func (cg CallGraph) PathsTo(sink CallSite) [][]CallSite {
+	paths := make([][]CallSite, 0)
+
+	for name, node := range cg.nodes {
+		if node.Function != sink.Function {
+			continue
+		}
+
+		cg.collectPathsTo(name, nil, make(strset), &paths)
+	}
+
+	return paths
+}
+
+// collectPathsTo walks backward from `name` to every root, prepending each
+// ancestor so the accumulated path reads root-to-sink. `visited` guards
+// against cycles within a single path without forbidding a node from
+// appearing on two different paths.
+func (cg CallGraph) collectPathsTo(name string, tail []CallSite, visited strset, out *[][]CallSite) {
+	if visited.Contains(name) {
+		return
+	}
+	visited.Add(name)
+	defer visited.Remove(name)
+
+	node := cg.nodes[name]
+	path := append([]CallSite{{
+		Function: node.Function,
+		Location: SourceLocation{Library: node.Library},
+	}}, tail...)
+
+	if len(node.CallsIn) == 0 {
+		*out = append(*out, path)
+		return
+	}
+
+	for _, call := range node.CallsIn {
+		cg.collectPathsTo(call.Caller, path, visited, out)
+	}
+}
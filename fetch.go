@@ -0,0 +1,471 @@
+package soaap
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+//
+// Fetcher retrieves the contents of a URL, the way pprof's `fetch` package
+// lets profiles be read from HTTP endpoints as well as local files.
+//
+// Implementations should return an error that includes the URL on failure,
+// so callers don't need to repeat it in context.
+//
+type Fetcher interface {
+	Fetch(url string) (io.ReadCloser, error)
+}
+
+//
+// ConditionalFetcher is implemented by Fetchers that can revalidate a
+// previously-cached blob against an ETag without re-downloading it, used by
+// OpenInput's on-disk cache. Fetchers that don't implement it (S3Fetcher,
+// for instance) are simply re-fetched on every call.
+//
+type ConditionalFetcher interface {
+	Fetcher
+
+	// FetchIfModified fetches `url`, skipping the transfer and returning
+	// notModified=true if `etag` is non-empty and still current. When the
+	// blob is fetched, newETag is the value to remember for next time (and
+	// may be empty if the server didn't send one).
+	FetchIfModified(url, etag string) (body io.ReadCloser, newETag string, notModified bool, err error)
+}
+
+var fetchers = map[string]Fetcher{
+	"http":  &HTTPFetcher{},
+	"https": &HTTPFetcher{},
+	"s3":    &S3Fetcher{},
+}
+
+//
+// RegisterFetcher installs f as the Fetcher used for URLs with the given
+// scheme, replacing any existing registration (including the http, https
+// and s3 fetchers this package registers by default). This is how
+// downstream users add support for schemes like gs or azblob without
+// needing a change here.
+//
+func RegisterFetcher(scheme string, f Fetcher) {
+	fetchers[scheme] = f
+}
+
+//
+// OpenInput opens `name` for reading, exactly like os.Open for a local
+// path. When `name` parses as a URL whose scheme has a registered Fetcher
+// (see RegisterFetcher; http, https and s3 are registered by default), it
+// is fetched instead, through the on-disk cache described below, and
+// copied into a temporary file so that callers can keep treating the
+// result as an *os.File — including inferring a codec from its extension,
+// the way CodecForFile already does for local paths.
+//
+// Blobs from a Fetcher that implements ConditionalFetcher are cached under
+// $XDG_CACHE_HOME/soaap (or ~/.cache/soaap), keyed by URL, and revalidated
+// by ETag, so running e.g. soaap-combine against the same CI artifact URLs
+// repeatedly doesn't re-download them every time. Other Fetchers are not
+// cached and fetch on every call.
+//
+func OpenInput(name string) (*os.File, error) {
+	scheme, ok := remoteScheme(name)
+	if !ok {
+		return os.Open(name)
+	}
+
+	fetcher, ok := fetchers[scheme]
+	if !ok {
+		return nil, fmt.Errorf("fetching '%s': no fetcher registered for scheme %q", name, scheme)
+	}
+
+	body, err := fetchCached(name, fetcher)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp("", "soaap-fetch-*"+path.Ext(name))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("fetching '%s': %w", name, err)
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	return tmp, nil
+}
+
+// IsRemote reports whether OpenInput would fetch `name` over the network
+// rather than opening it as a local path - useful for callers (like
+// -watch) that can only meaningfully poll a local file for changes.
+func IsRemote(name string) bool {
+	_, ok := remoteScheme(name)
+	return ok
+}
+
+// remoteScheme reports the scheme of `name`, if it parses as an absolute
+// URL. A single-letter scheme is treated as a Windows drive letter
+// ("C:\graphs\a.graph") rather than a URL, not a remote reference.
+func remoteScheme(name string) (string, bool) {
+	u, err := url.Parse(name)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+		return "", false
+	}
+
+	return u.Scheme, true
+}
+
+// fetchCached fetches `url` via `fetcher`. When the fetcher is a
+// ConditionalFetcher, it caches the result under cacheDir and reuses it on
+// subsequent calls after an ETag revalidation; any other fetcher is called
+// directly, with no caching. If the cache directory can't be created, it
+// falls back to fetching directly every time.
+func fetchCached(url string, fetcher Fetcher) (io.ReadCloser, error) {
+	cond, ok := fetcher.(ConditionalFetcher)
+	if !ok {
+		return fetcher.Fetch(url)
+	}
+
+	dir, err := cacheDir()
+	if err != nil {
+		return fetcher.Fetch(url)
+	}
+
+	key := cacheKey(url)
+	blobPath := filepath.Join(dir, key+".blob")
+	etagPath := filepath.Join(dir, key+".etag")
+
+	etag := ""
+	if b, err := os.ReadFile(etagPath); err == nil {
+		etag = string(b)
+	}
+
+	body, newETag, notModified, err := cond.FetchIfModified(url, etag)
+	if err != nil {
+		return nil, err
+	}
+
+	if notModified {
+		if f, err := os.Open(blobPath); err == nil {
+			return f, nil
+		}
+		// The cached blob vanished out from under us: fall through and
+		// re-fetch in full.
+		body, newETag, _, err = cond.FetchIfModified(url, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(blobPath, data, 0o644); err == nil && newETag != "" {
+		os.WriteFile(etagPath, []byte(newETag), 0o644)
+	}
+
+	return io.NopCloser(strings.NewReader(string(data))), nil
+}
+
+func cacheDir() (string, error) {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".cache")
+	}
+
+	dir = filepath.Join(dir, "soaap")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+func cacheKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+//
+// HTTPFetcher fetches a URL over HTTP(S). Authorization, if not already
+// supplied via Headers, falls back to a matching ~/.netrc entry (or
+// $NETRC) so CI credentials already configured for curl/wget work here
+// too.
+//
+type HTTPFetcher struct {
+	// Timeout bounds each request; zero means 30 seconds.
+	Timeout time.Duration
+
+	// Headers are set on every request, e.g. {"Authorization": "Bearer ..."}.
+	Headers map[string]string
+}
+
+func (f *HTTPFetcher) Fetch(url string) (io.ReadCloser, error) {
+	body, _, _, err := f.fetch(url, "")
+	return body, err
+}
+
+func (f *HTTPFetcher) FetchIfModified(url, etag string) (io.ReadCloser, string, bool, error) {
+	return f.fetch(url, etag)
+}
+
+func (f *HTTPFetcher) fetch(rawURL, etag string) (io.ReadCloser, string, bool, error) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	for k, v := range f.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	if req.Header.Get("Authorization") == "" {
+		if user, pass, ok := netrcAuth(req.URL.Hostname()); ok {
+			req.SetBasicAuth(user, pass)
+		}
+	}
+
+	timeout := f.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	resp, err := (&http.Client{Timeout: timeout}).Do(req)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("fetching '%s': %w", rawURL, err)
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, etag, true, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, "", false, fmt.Errorf("fetching '%s': %s", rawURL, resp.Status)
+	}
+
+	return resp.Body, resp.Header.Get("ETag"), false, nil
+}
+
+// netrcAuth looks up a login/password pair for `host` from ~/.netrc (or the
+// file named by $NETRC), in the same "machine/login/password" format
+// curl and wget use. It returns ok=false if there's no netrc file, or no
+// matching entry.
+func netrcAuth(host string) (string, string, bool) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", false
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", false
+	}
+
+	fields := strings.Fields(string(data))
+
+	var machine, login, password string
+	check := func() (string, string, bool) {
+		return login, password, machine == host && login != "" && password != ""
+	}
+
+	for i := 0; i < len(fields)-1; i++ {
+		switch fields[i] {
+		case "machine":
+			if user, pass, ok := check(); ok {
+				return user, pass, true
+			}
+			machine, login, password = fields[i+1], "", ""
+
+		case "login":
+			login = fields[i+1]
+
+		case "password":
+			password = fields[i+1]
+		}
+	}
+
+	return check()
+}
+
+//
+// S3Fetcher fetches an object from Amazon S3, given a "s3://bucket/key"
+// URL, signing the request with Signature Version 4 using the standard
+// AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY / AWS_SESSION_TOKEN
+// environment variables. It doesn't implement ConditionalFetcher, so
+// fetchCached re-downloads the object on every call.
+//
+// signS3Request below hand-rolls the SigV4 canonical request, string-to-
+// sign and HMAC key-derivation chain instead of calling an AWS SDK, which
+// this module was asked for and doesn't depend on. Unlike the other
+// hand-rolled substitutions in this module, a signing bug here doesn't
+// fail loudly: S3 just returns a 403 with no indication of which part of
+// the signature was wrong, and there is nothing here (no golden test
+// vectors, no integration test against a real bucket) exercising it.
+// This needs sign-off before it ships against production S3 traffic, not
+// a silent pass.
+//
+type S3Fetcher struct {
+	// Region overrides $AWS_REGION / $AWS_DEFAULT_REGION; "us-east-1" is
+	// used if none of the three are set.
+	Region string
+}
+
+func (f *S3Fetcher) Fetch(rawURL string) (io.ReadCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := u.Host
+	key := strings.TrimPrefix(u.Path, "/")
+	region := f.region()
+
+	endpoint := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, key)
+
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := signS3Request(req, region,
+		os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN")); err != nil {
+		return nil, fmt.Errorf("fetching '%s': %w", rawURL, err)
+	}
+
+	resp, err := (&http.Client{Timeout: 60 * time.Second}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching '%s': %w", rawURL, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching '%s': %s", rawURL, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+func (f *S3Fetcher) region() string {
+	for _, r := range []string{f.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")} {
+		if r != "" {
+			return r
+		}
+	}
+	return "us-east-1"
+}
+
+// signS3Request adds the AWS Signature Version 4 headers for a GET request
+// with an empty body (signing the SHA-256 of the empty payload), the minimal
+// subset of
+// https://docs.aws.amazon.com/general/latest/gr/sigv4-signing.html needed
+// to fetch an object from S3 without pulling in the AWS SDK.
+func signS3Request(req *http.Request, region, accessKey, secretKey, sessionToken string) error {
+	if accessKey == "" || secretKey == "" {
+		return fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+	payloadHash := sha256Hex(nil)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if sessionToken != "" {
+		headers["x-amz-security-token"] = sessionToken
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	credentialScope := dateStamp + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		credentialScope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256(hmacSHA256(hmacSHA256(hmacSHA256(
+		[]byte("AWS4"+secretKey), dateStamp), region), "s3"), "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+	req.Host = req.URL.Host
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, credentialScope, signedHeaders, signature))
+
+	return nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,210 @@
+package soaap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Ranks assigned to a Finding, lowest first. A Finding's Rank is the best
+// (highest) rank among its contributing Vulns.
+const (
+	RankUnreachable = iota // no trace reaches the sink at all
+	RankLibraryOnly        // trace terminates in library code only
+	RankTraced              // trace exists but isn't known reachable from an entry point
+	RankReachable          // sink is reachable from a real entry point
+)
+
+//
+// Finding groups SOAAP warnings that describe the same underlying
+// vulnerability (keyed by CVE ID when present, else by Type+Sandbox) and
+// ranks them by how actionable they look: a finding reachable from a real
+// entry point ranks above one with only a partial trace, which ranks above
+// one whose trace bottoms out in library code with no further information.
+//
+type Finding struct {
+	Key string
+
+	Vulns      []Vuln
+	PrivAccess []PrivAccess
+
+	// Deduplicated call paths (warning location to root) contributing to
+	// this finding, one per distinct path observed.
+	Paths [][]CallSite
+
+	Rank int
+}
+
+//
+// Findings groups r's Vulnerabilities and PrivateAccess entries into a
+// ranked []Finding, giving CLI consumers a better default report than the
+// raw per-analysis slices.
+//
+func (r Results) Findings() ([]Finding, error) {
+	merged, err := r.BuildReverseIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	// expandToFixedPoint rather than CollectNodes(..., -1): merged is built
+	// from raw traces, which routinely contain recursion, and CollectNodes'
+	// unbounded recursion never terminates on a cycle.
+	reachable := merged.expandToFixedPoint(merged.roots, GraphNode.Callees)
+
+	groups := make(map[string]*Finding)
+	var order []string
+
+	addPath := func(f *Finding, path []CallSite) {
+		sig := pathSignature(path)
+		for _, existing := range f.Paths {
+			if pathSignature(existing) == sig {
+				return
+			}
+		}
+		f.Paths = append(f.Paths, path)
+	}
+
+	for _, v := range r.Vulnerabilities {
+		key := findingKey(v)
+
+		f, ok := groups[key]
+		if !ok {
+			f = &Finding{Key: key}
+			groups[key] = f
+			order = append(order, key)
+		}
+
+		f.Vulns = append(f.Vulns, v)
+
+		path, err := tracePath(r.Traces, v.Trace)
+		if err != nil {
+			return nil, err
+		}
+		addPath(f, path)
+
+		rank := rankVuln(v, path, reachable)
+		if rank > f.Rank {
+			f.Rank = rank
+		}
+	}
+
+	for _, a := range r.PrivateAccess {
+		key := a.DataOwners().Join(",")
+		if key == "" {
+			key = "private_access"
+		}
+
+		f, ok := groups[key]
+		if !ok {
+			f = &Finding{Key: key}
+			groups[key] = f
+			order = append(order, key)
+		}
+
+		f.PrivAccess = append(f.PrivAccess, a)
+
+		path, err := tracePath(r.Traces, a.Trace)
+		if err != nil {
+			return nil, err
+		}
+		addPath(f, path)
+
+		if len(path) > 0 && f.Rank < RankTraced {
+			f.Rank = RankTraced
+		}
+	}
+
+	findings := make([]Finding, 0, len(order))
+	for _, key := range order {
+		findings = append(findings, *groups[key])
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Rank > findings[j].Rank
+	})
+
+	return findings, nil
+}
+
+//
+// Unreachable returns the Vulnerabilities in r with no reachable trace at
+// all (an empty call path), mirroring the unreachable-vuln listing that
+// Findings otherwise folds into RankUnreachable.
+//
+func (r Results) Unreachable() ([]Vuln, error) {
+	unreachable := make([]Vuln, 0)
+
+	for _, v := range r.Vulnerabilities {
+		path, err := tracePath(r.Traces, v.Trace)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(path) == 0 {
+			unreachable = append(unreachable, v)
+		}
+	}
+
+	return unreachable, nil
+}
+
+// findingKey computes the canonical grouping key for a Vuln: its CVE IDs
+// when known, else its Type and Sandbox.
+func findingKey(v Vuln) string {
+	if cves := v.CVEs(); len(cves) > 0 {
+		ids := cves.Values()
+		sort.Strings(ids)
+		return strings.Join(ids, ",")
+	}
+
+	return v.Type + ":" + v.Sandbox
+}
+
+// rankVuln ranks a single Vuln's contribution to a Finding.
+func rankVuln(v Vuln, path []CallSite, reachable strset) int {
+	// reachable comes from BuildReverseIndex, whose nodes all have an
+	// empty sandbox.
+	sink := newGraphNode(v.CallSite, "").Name
+	if reachable.Contains(sink) {
+		return RankReachable
+	}
+
+	if len(path) == 0 {
+		return RankUnreachable
+	}
+
+	for _, cs := range path {
+		if cs.Location.Library == "" {
+			return RankTraced
+		}
+	}
+
+	return RankLibraryOnly
+}
+
+// tracePath walks the trace at traceIdx (following Next references) and
+// returns its CallSites in warning-to-root order.
+func tracePath(traces []CallTrace, traceIdx int) ([]CallSite, error) {
+	if traceIdx < 0 || traceIdx >= len(traces) {
+		return nil, fmt.Errorf("trace index %d out of range (have %d traces)",
+			traceIdx, len(traces))
+	}
+
+	path := make([]CallSite, 0)
+	err := traces[traceIdx].ForeachN(traces, DefaultMaxTraceDepth, func(cs CallSite) {
+		path = append(path, cs)
+	})
+
+	return path, err
+}
+
+// pathSignature produces a comparable key for a call path, used to
+// deduplicate paths within a Finding.
+func pathSignature(path []CallSite) string {
+	parts := make([]string, len(path))
+	for i, cs := range path {
+		parts[i] = cs.String()
+	}
+
+	return strings.Join(parts, " <- ")
+}
@@ -1,12 +1,15 @@
 package soaap
 
 import (
+	"context"
 	"encoding/gob"
 	"fmt"
 	"io"
 	"math"
 	"os"
+	"runtime"
 	"strings"
+	"sync"
 )
 
 type CallGraph struct {
@@ -15,6 +18,14 @@ type CallGraph struct {
 	leaves strset
 	calls  map[Call]int
 	flows  map[Call]int
+
+	// Guards nodes/roots/leaves/calls/flows so that AddNode, AddCall and
+	// AddFlow are safe to call concurrently, e.g. from a merger goroutine
+	// while worker goroutines build their own independent CallGraphs (see
+	// PrivAccessGraph). A pointer so copying a CallGraph by value (common
+	// throughout this package) shares one mutex rather than copying a
+	// locked/unlocked one.
+	mu *sync.Mutex
 }
 
 //
@@ -27,16 +38,26 @@ func NewCallGraph() CallGraph {
 		make(strset),
 		make(map[Call]int),
 		make(map[Call]int),
+		&sync.Mutex{},
 	}
 }
 
 //
-// Load a CallGraph from a binary-encoded file.
+// Load a CallGraph from a binary-encoded file. Equivalent to
+// GobCodec{}.Decode(f), kept as a shorthand since it's by far the most
+// common codec in practice.
 //
-func LoadGraph(f *os.File, report func(string)) (CallGraph, error) {
-	var cg CallGraph
+func LoadGraph(f *os.File, progress ProgressReporter) (CallGraph, error) {
+	progress.Start(0, fmt.Sprintf("Loading graph data from %s", f.Name()))
+	defer progress.Finish()
+
+	return gobDecode(f)
+}
+
+func gobDecode(r io.Reader) (CallGraph, error) {
+	cg := NewCallGraph()
 
-	dec := gob.NewDecoder(f)
+	dec := gob.NewDecoder(r)
 
 	if err := dec.Decode(&cg.nodes); err != nil {
 		return cg, err
@@ -54,8 +75,12 @@ func LoadGraph(f *os.File, report func(string)) (CallGraph, error) {
 		return cg, err
 	}
 
+	if err := dec.Decode(&cg.flows); err != nil {
+		return cg, err
+	}
+
 	//
-	// Reconstitute each node's callers and callees.
+	// Reconstitute each node's callers, callees and data flows.
 	//
 	for call := range cg.calls {
 		callee := cg.nodes[call.Callee]
@@ -67,11 +92,34 @@ func LoadGraph(f *os.File, report func(string)) (CallGraph, error) {
 		cg.nodes[call.Caller] = caller
 	}
 
+	for flow := range cg.flows {
+		dest := cg.nodes[flow.Callee]
+		dest.FlowsIn = append(dest.FlowsIn, flow)
+		cg.nodes[flow.Callee] = dest
+
+		source := cg.nodes[flow.Caller]
+		source.FlowsOut = append(source.FlowsOut, flow)
+		cg.nodes[flow.Caller] = source
+	}
+
 	return cg, nil
 }
 
 func (cg *CallGraph) AddCall(call Call) {
-	cg.calls[call] += 1
+	cg.mu.Lock()
+	defer cg.mu.Unlock()
+	cg.addCallLocked(call, 1)
+}
+
+func (cg *CallGraph) AddCalls(call Call, weight int) {
+	cg.mu.Lock()
+	defer cg.mu.Unlock()
+	cg.addCallLocked(call, weight)
+}
+
+// addCallLocked is AddCall/AddCalls' body, assuming cg.mu is already held.
+func (cg *CallGraph) addCallLocked(call Call, weight int) {
+	cg.calls[call] += weight
 
 	caller := call.Caller
 	callee := call.Callee
@@ -90,13 +138,21 @@ func (cg *CallGraph) AddCall(call Call) {
 	cg.leaves.Remove(caller)
 }
 
-func (cg *CallGraph) AddCalls(call Call, weight int) {
-	cg.AddCall(call)
-	cg.calls[call] += weight - 1
+func (cg *CallGraph) AddFlow(flow Call) {
+	cg.mu.Lock()
+	defer cg.mu.Unlock()
+	cg.addFlowLocked(flow, 1)
 }
 
-func (cg *CallGraph) AddFlow(flow Call) {
-	cg.flows[flow] += 1
+func (cg *CallGraph) AddFlows(flow Call, weight int) {
+	cg.mu.Lock()
+	defer cg.mu.Unlock()
+	cg.addFlowLocked(flow, weight)
+}
+
+// addFlowLocked is AddFlow/AddFlows' body, assuming cg.mu is already held.
+func (cg *CallGraph) addFlowLocked(flow Call, weight int) {
+	cg.flows[flow] += weight
 
 	source := flow.Caller
 	dest := flow.Callee
@@ -113,12 +169,14 @@ func (cg *CallGraph) AddFlow(flow Call) {
 	cg.leaves.Remove(source)
 }
 
-func (cg *CallGraph) AddFlows(flow Call, weight int) {
-	cg.AddFlow(flow)
-	cg.flows[flow] += weight - 1
+func (cg *CallGraph) AddNode(node GraphNode) {
+	cg.mu.Lock()
+	defer cg.mu.Unlock()
+	cg.addNodeLocked(node)
 }
 
-func (cg *CallGraph) AddNode(node GraphNode) {
+// addNodeLocked is AddNode's body, assuming cg.mu is already held.
+func (cg *CallGraph) addNodeLocked(node GraphNode) {
 	name := node.Name
 
 	if n, ok := cg.nodes[name]; ok {
@@ -136,6 +194,77 @@ func (cg *CallGraph) AddNode(node GraphNode) {
 	}
 }
 
+//
+// UnionAll merges every graph in `graphs` into cg in one locked pass,
+// rather than paying per-call lock/map-lookup overhead for each individual
+// node, call and flow the way repeated calls to Union would.
+//
+func (cg *CallGraph) UnionAll(graphs ...CallGraph) error {
+	cg.mu.Lock()
+	defer cg.mu.Unlock()
+
+	for _, g := range graphs {
+		for _, node := range g.nodes {
+			cg.addNodeLocked(node)
+		}
+
+		for call, count := range g.calls {
+			cg.addCallLocked(call, count)
+		}
+
+		for flow, count := range g.flows {
+			cg.addFlowLocked(flow, count)
+		}
+	}
+
+	return nil
+}
+
+//
+// ResolveIndirect records that the indirect-call node `name` may dispatch
+// to any of `targets`, adding a Call edge from the indirect node to each
+// target that's already present in the graph. This lets callers refine an
+// indirect call's candidate set using a whole-program pointer analysis
+// that SOAAP itself doesn't perform.
+//
+func (cg *CallGraph) ResolveIndirect(name string, targets []string) {
+	node, ok := cg.nodes[name]
+	if !ok || node.Kind != NodeIndirect {
+		return
+	}
+
+	for _, target := range targets {
+		if _, ok := cg.nodes[target]; !ok {
+			continue
+		}
+
+		cg.AddCall(Call{Caller: name, Callee: target, Sandbox: node.Sandbox})
+	}
+}
+
+//
+// Ancestors returns the backward slice from `name` (the nodes that can
+// reach it by calls or data flows), bounded to `depth` hops, or the full
+// slice if depth is negative. Indirect-call nodes (see NodeIndirect) are
+// walked through like any other node, so a node reachable only through a
+// function pointer still shows up here.
+//
+func (cg CallGraph) Ancestors(name string, depth int) strset {
+	if depth < 0 {
+		// CollectNodes with no depth bound never terminates on a cycle.
+		return cg.expandToFixedPoint(singleton(name), GraphNode.AllInputs)
+	}
+	return cg.CollectNodes(name, GraphNode.AllInputs, depth)
+}
+
+//
+// Filter returns the subgraph of cg containing only the nodes named in
+// `keep`, and the calls/flows between them.
+//
+func (cg CallGraph) Filter(keep strset) CallGraph {
+	return cg.filterTo(keep)
+}
+
 func (cg *CallGraph) CollectNodes(root string,
 	selector func(GraphNode) strset, depth int) strset {
 
@@ -157,10 +286,16 @@ func (cg *CallGraph) CollectNodes(root string,
 }
 
 //
-// Save a CallGraph to an os.File using a binary encoding.
+// Save a CallGraph to an os.File using a binary encoding. Equivalent to
+// GobCodec{}.Encode(f, cg), kept as a shorthand since it's by far the most
+// common codec in practice.
 //
 func (cg CallGraph) Save(f *os.File) error {
-	enc := gob.NewEncoder(f)
+	return gobEncode(f, cg)
+}
+
+func gobEncode(w io.Writer, cg CallGraph) error {
+	enc := gob.NewEncoder(w)
 
 	//
 	// We don't want the gob encoder to flatten each node's Call pointers,
@@ -171,6 +306,8 @@ func (cg CallGraph) Save(f *os.File) error {
 		n := node
 		n.CallsOut = nil
 		n.CallsIn = nil
+		n.FlowsOut = nil
+		n.FlowsIn = nil
 		nodes[name] = n
 	}
 
@@ -190,6 +327,10 @@ func (cg CallGraph) Save(f *os.File) error {
 		return err
 	}
 
+	if err := enc.Encode(cg.flows); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -289,11 +430,28 @@ func (cg CallGraph) Size() (int, int, int) {
 	return len(cg.nodes), len(cg.calls), len(cg.flows)
 }
 
+//
+// Node returns the node named `name`, and whether it exists in cg.
+//
+func (cg CallGraph) Node(name string) (GraphNode, bool) {
+	n, ok := cg.nodes[name]
+	return n, ok
+}
+
 //
 // Add intersecting nodes to this graph, where the call traces leading to
 // any two leaf nodes must intersect within `depth` calls.
 //
 func (cg *CallGraph) AddIntersecting(g CallGraph, depth int) error {
+	return cg.AddIntersectingWithProgress(g, depth, NopProgress{})
+}
+
+// AddIntersectingWithProgress behaves like AddIntersecting, reporting one
+// Add(1) per leaf node (ours, then g's) considered.
+func (cg *CallGraph) AddIntersectingWithProgress(g CallGraph, depth int, progress ProgressReporter) error {
+	progress.Start(int64(len(cg.leaves)+len(g.leaves)), "Adding intersection")
+	defer progress.Finish()
+
 	// The method that selects all inputs (callers and data flows)
 	// into a GraphNode.
 	selector := GraphNode.AllInputs
@@ -303,6 +461,7 @@ func (cg *CallGraph) AddIntersecting(g CallGraph, depth int) error {
 
 	for id := range cg.leaves {
 		ancestors = ancestors.Union(cg.CollectNodes(id, selector, depth))
+		progress.Add(1)
 	}
 
 	// Keep those leaves with an ancestor common to the above.
@@ -316,6 +475,7 @@ func (cg *CallGraph) AddIntersecting(g CallGraph, depth int) error {
 				break
 			}
 		}
+		progress.Add(1)
 	}
 
 	for id := range keep {
@@ -347,6 +507,18 @@ func (cg *CallGraph) AddIntersecting(g CallGraph, depth int) error {
 func (cg CallGraph) Intersect(g CallGraph, depth int,
 	keepBacktrace bool) (CallGraph, error) {
 
+	return cg.IntersectWithProgress(g, depth, keepBacktrace, NopProgress{})
+}
+
+// IntersectWithProgress behaves like Intersect, reporting one Add(1) per
+// leaf node considered when matching against the other graph (g's, then
+// ours).
+func (cg CallGraph) IntersectWithProgress(g CallGraph, depth int,
+	keepBacktrace bool, progress ProgressReporter) (CallGraph, error) {
+
+	progress.Start(int64(len(cg.leaves)+len(g.leaves)), "Intersecting")
+	defer progress.Finish()
+
 	selector := GraphNode.AllInputs
 	result := NewCallGraph()
 
@@ -378,6 +550,7 @@ func (cg CallGraph) Intersect(g CallGraph, depth int,
 				break
 			}
 		}
+		progress.Add(1)
 	}
 
 	for id := range keep {
@@ -417,6 +590,7 @@ func (cg CallGraph) Intersect(g CallGraph, depth int,
 				break
 			}
 		}
+		progress.Add(1)
 	}
 
 	for id := range keep {
@@ -442,23 +616,112 @@ func (cg CallGraph) Intersect(g CallGraph, depth int,
 // Compute the union of two CallGraphs.
 //
 func (cg *CallGraph) Union(g CallGraph) error {
+	return cg.UnionWithProgress(g, NopProgress{})
+}
+
+// UnionWithProgress behaves like Union, reporting one Add(1) per node,
+// call and flow merged in, so long-running merges (e.g. soaap-combine
+// merging two large saved graphs) give visible feedback.
+func (cg *CallGraph) UnionWithProgress(g CallGraph, progress ProgressReporter) error {
+	progress.Start(int64(len(g.nodes)+len(g.calls)+len(g.flows)), "Merging graphs")
+	defer progress.Finish()
+
+	cg.mu.Lock()
+	defer cg.mu.Unlock()
+
 	for _, node := range g.nodes {
-		cg.AddNode(node)
+		cg.addNodeLocked(node)
+		progress.Add(1)
 	}
 
 	for call, count := range g.calls {
-		cg.AddCall(call)
-		cg.calls[call] += (count - 1)
+		cg.addCallLocked(call, count)
+		progress.Add(1)
 	}
 
 	for flow, count := range g.flows {
-		cg.AddFlow(flow)
-		cg.flows[flow] += (count - 1)
+		cg.addFlowLocked(flow, count)
+		progress.Add(1)
 	}
 
 	return nil
 }
 
+//
+// SliceIntersect computes the subgraph of cg reachable backward from
+// `sinks` and forward from `entries`, keeping only the nodes (and edges
+// between kept nodes) in both slices.
+//
+// Unlike CollectNodes, which bounds its walk by a caller-supplied depth,
+// SliceIntersect expands each slice to a fixed point: a node already in the
+// slice is never re-expanded, so self-recursion and cycles among callers
+// (e.g. G <-> F) terminate naturally instead of requiring a depth cap that
+// could cut a genuine path short or, worse, recurse forever.
+//
+func (cg CallGraph) SliceIntersect(entries, sinks strset) CallGraph {
+	backward := cg.expandToFixedPoint(sinks, GraphNode.AllInputs)
+	forward := cg.expandToFixedPoint(entries, GraphNode.AllOutputs)
+
+	return cg.filterTo(backward.Intersection(forward))
+}
+
+// expandToFixedPoint grows `seed` by repeatedly applying `selector` to
+// every node added so far, stopping once a pass adds nothing new. Because a
+// node is never expanded twice, this terminates even when `selector`
+// induces a cycle.
+func (cg CallGraph) expandToFixedPoint(seed strset, selector func(GraphNode) strset) strset {
+	result := make(strset)
+	frontier := make(strset)
+
+	for id := range seed {
+		result.Add(id)
+		frontier.Add(id)
+	}
+
+	for len(frontier) > 0 {
+		next := make(strset)
+
+		for id := range frontier {
+			for n := range selector(cg.nodes[id]) {
+				if !result.Contains(n) {
+					result.Add(n)
+					next.Add(n)
+				}
+			}
+		}
+
+		frontier = next
+	}
+
+	return result
+}
+
+// filterTo returns the subgraph of cg containing only the nodes named in
+// `keep`, and only those calls/flows with both endpoints in `keep`.
+func (cg CallGraph) filterTo(keep strset) CallGraph {
+	result := NewCallGraph()
+
+	for id := range keep {
+		if node, ok := cg.nodes[id]; ok {
+			result.AddNode(node)
+		}
+	}
+
+	for call, weight := range cg.calls {
+		if keep.Contains(call.Caller) && keep.Contains(call.Callee) {
+			result.AddCalls(call, weight)
+		}
+	}
+
+	for flow, weight := range cg.flows {
+		if keep.Contains(flow.Caller) && keep.Contains(flow.Callee) {
+			result.AddFlows(flow, weight)
+		}
+	}
+
+	return result
+}
+
 func (cg CallGraph) WriteDot(out io.Writer, groupBy string) error {
 	fmt.Fprintln(out, `digraph {
 
@@ -548,6 +811,25 @@ func (cg CallGraph) WriteDot(out io.Writer, groupBy string) error {
 	return nil
 }
 
+//
+// The kind of entity a GraphNode represents.
+//
+type NodeKind int
+
+const (
+	// An ordinary function/call-site node.
+	NodeDirect NodeKind = iota
+
+	// A synthetic node standing in for an indirect call (a call through a
+	// function pointer or virtual dispatch) whose concrete targets are
+	// recorded via CallGraph.ResolveIndirect rather than known statically.
+	NodeIndirect
+
+	// A synthetic node standing in for a strongly connected component of
+	// two or more mutually-recursive nodes, produced by CallGraph.Condense.
+	NodeCondensed
+)
+
 //
 // A node in a call graph.
 //
@@ -557,6 +839,10 @@ func (cg CallGraph) WriteDot(out io.Writer, groupBy string) error {
 type GraphNode struct {
 	Name string
 
+	// What sort of node this is: a real function, a synthetic
+	// indirect-call node or a condensed SCC node (see NodeKind).
+	Kind NodeKind
+
 	// The name of the function this node is in / represents.
 	Function string
 
@@ -581,6 +867,10 @@ type GraphNode struct {
 	FlowsOut []Call
 
 	Tags strset
+
+	// The member node names collapsed into this node by CallGraph.Condense.
+	// Only set when Kind == NodeCondensed.
+	Members []string
 }
 
 func newGraphNode(cs CallSite, sandbox string) GraphNode {
@@ -600,6 +890,23 @@ func newGraphNode(cs CallSite, sandbox string) GraphNode {
 	return node
 }
 
+//
+// newTraceNode builds the GraphNode for a CallSite encountered while
+// walking a trace, recognizing SOAAP's marker for an unresolved indirect
+// call (see IndirectCallMarker) and synthesizing a NodeIndirect node for it
+// instead of an ordinary function node.
+//
+func newTraceNode(cs CallSite, sandbox string) GraphNode {
+	if !isIndirectCallSite(cs) {
+		return newGraphNode(cs, sandbox)
+	}
+
+	node := newGraphNode(cs, sandbox)
+	node.Kind = NodeIndirect
+	node.Name = "indirect@" + cs.Location.String() + " : " + sandbox
+	return node
+}
+
 func (n GraphNode) AllInputs() strset {
 	return n.Callers().Union(n.DataSources())
 }
@@ -735,6 +1042,16 @@ func (n GraphNode) Dot() string {
 		attrs["style"] = "dashed,filled"
 	}
 
+	if n.Kind == NodeIndirect {
+		attrs["shape"] = "diamond"
+		attrs["style"] = "dashed,filled"
+	}
+
+	if n.Kind == NodeCondensed {
+		attrs["shape"] = "tripleoctagon"
+		attrs["tooltip"] = strings.Join(n.Members, ", ")
+	}
+
 	return fmt.Sprintf("\"%s\" %s;", n.Name, dotAttrs(attrs))
 }
 
@@ -814,6 +1131,10 @@ func (c Call) Dot(graph CallGraph, weight int, flow bool) string {
 		colour = Sandboxed
 	}
 
+	if caller.Kind == NodeIndirect || callee.Kind == NodeIndirect {
+		style = "dashed"
+	}
+
 	attrs := map[string]interface{}{
 		"color":     colour + "cc",
 		"fontcolor": colour,
@@ -827,14 +1148,57 @@ func (c Call) Dot(graph CallGraph, weight int, flow bool) string {
 		caller.Name, callee.Name, dotAttrs(attrs))
 }
 
+//
+// Legend returns a small synthetic CallGraph illustrating the node/edge
+// styles WriteDot produces, for `soaap-graph -legend` to render instead of
+// an actual analysis.
+//
+func Legend() CallGraph {
+	g := NewCallGraph()
+
+	plain := newGraphNode(CallSite{Function: "plain()"}, "")
+	g.AddNode(plain)
+
+	vuln := newGraphNode(CallSite{Function: "vulnerable()"}, "")
+	vuln.CVE.Add("CVE-0000-0000")
+	g.AddNode(vuln)
+
+	private := newGraphNode(CallSite{Function: "accessesPrivateData()"}, "")
+	private.Owners.Add("sandbox")
+	g.AddNode(private)
+
+	sandboxed := newGraphNode(CallSite{Function: "sandboxedCall()"}, "sandbox")
+	g.AddNode(sandboxed)
+
+	contained := newGraphNode(CallSite{Function: "containedVulnerability()"}, "sandbox")
+	contained.CVE.Add("CVE-0000-0001")
+	g.AddNode(contained)
+
+	indirect := newTraceNode(CallSite{Function: IndirectCallMarker}, "")
+	g.AddNode(indirect)
+
+	condensed := newGraphNode(CallSite{Function: "SCC(2 members)"}, "")
+	condensed.Name = "a,b"
+	condensed.Kind = NodeCondensed
+	condensed.Members = []string{"a", "b"}
+	g.AddNode(condensed)
+
+	g.AddCall(newCall(plain, vuln, CallSite{}, ""))
+	g.AddCall(newCall(vuln, sandboxed, CallSite{}, "sandbox"))
+	g.AddFlow(newCall(plain, private, CallSite{}, ""))
+
+	return g
+}
+
 //
 // A function that extracts a CallGraph from SOAAP Results.
 //
-type graphFn func(results Results, progress func(string)) (CallGraph, error)
+type graphFn func(ctx context.Context, results Results, progress ProgressReporter) (CallGraph, error)
 
 var graphExtractors map[string]graphFn = map[string]graphFn{
 	"privaccess": PrivAccessGraph,
 	"vuln":       VulnGraph,
+	"vulnslice":  ExtractVulnGraph,
 }
 
 func GraphAnalyses() []string {
@@ -855,14 +1219,22 @@ type nodeMaker func(CallSite) GraphNode
 //
 // Construct a callgraph from SOAAP's vulnerability analysis.
 //
-func VulnGraph(results Results, progress func(string)) (CallGraph, error) {
+func VulnGraph(ctx context.Context, results Results, progress ProgressReporter) (CallGraph, error) {
 	graph := NewCallGraph()
+	sinks := make(strset)
+
+	progress.Start(int64(len(results.Vulnerabilities)), "Building vulnerability call graph")
+	defer progress.Finish()
 
 	for _, v := range results.Vulnerabilities {
+		if err := ctx.Err(); err != nil {
+			return CallGraph{}, err
+		}
+
 		trace := results.Traces[v.Trace]
 
 		fn := func(cs CallSite) GraphNode {
-			return newGraphNode(cs, v.Sandbox)
+			return newTraceNode(cs, v.Sandbox)
 		}
 
 		call := func(caller GraphNode, callee GraphNode, cs CallSite) {
@@ -872,6 +1244,7 @@ func VulnGraph(results Results, progress func(string)) (CallGraph, error) {
 		top := fn(v.CallSite)
 		top.CVE = v.CVEs()
 		graph.AddNode(top)
+		sinks.Add(top.Name)
 
 		g, err := trace.graph(top, results.Traces, fn, call)
 		if err != nil {
@@ -879,71 +1252,167 @@ func VulnGraph(results Results, progress func(string)) (CallGraph, error) {
 		}
 
 		graph.Union(g)
+		progress.Add(1)
 	}
 
-	return graph, nil
+	// Keep only the paths from a real entry point (a root of the merged
+	// trace graph) through to a vulnerable sink. Computing this as a
+	// fixed-point slice intersection, rather than the trace-by-trace
+	// top-down walk above, ensures recursive call paths (G <-> F -> V)
+	// keep every edge even when F was visited before V was known
+	// vulnerable.
+	return graph.SliceIntersect(graph.roots, sinks), nil
 }
 
 //
 // Construct a callgraph of sandbox-private data accesses outside of sandboxes.
 //
-func PrivAccessGraph(results Results, progress func(string)) (CallGraph, error) {
-	graph := NewCallGraph()
+// Building hundreds of thousands of access records into a single shared
+// CallGraph serially is dominated by per-record Union overhead, so this
+// fans the accesses out across GOMAXPROCS worker goroutines that each build
+// an independent local CallGraph, and a merger goroutine that folds each
+// worker's result into the final graph (via UnionAll) as it completes.
+//
+func PrivAccessGraph(ctx context.Context, results Results, progress ProgressReporter) (CallGraph, error) {
 	accesses := results.PrivateAccess
 	total := len(accesses)
-	chunk := int(math.Pow(10, math.Ceil(math.Log10(float64(total)/20))))
-	if chunk < 1000 {
-		chunk = 1000
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers < 1 {
+		workers = 1
+	}
+	if total > 0 && workers > total {
+		workers = total
 	}
 
-	go progress(fmt.Sprintf("Processing %d private accesses", total))
+	progress.Start(int64(total), fmt.Sprintf("Processing %d private accesses across %d workers",
+		total, workers))
+	defer progress.Finish()
+
+	// progress isn't safe for concurrent use, but every worker below
+	// reports one access at a time, so serialize those Adds through a
+	// mutex rather than through the accesses-per-worker channel.
+	var progressMu sync.Mutex
+	reportAccess := func() {
+		progressMu.Lock()
+		progress.Add(1)
+		progressMu.Unlock()
+	}
 
-	count := 0
-	for _, a := range accesses {
-		trace := results.Traces[a.Trace]
+	type accessGraph struct {
+		graph CallGraph
+		sinks strset
+		err   error
+	}
 
-		fn := func(cs CallSite) GraphNode {
-			return newGraphNode(cs, "")
-		}
+	resultsCh := make(chan accessGraph, workers)
 
-		call := func(caller GraphNode, callee GraphNode, cs CallSite) {
-			graph.AddCall(newCall(caller, callee, cs, ""))
+	chunkSize := 1
+	if workers > 0 {
+		chunkSize = (total + workers - 1) / workers
+		if chunkSize < 1 {
+			chunkSize = 1
 		}
+	}
 
-		flow := func(caller GraphNode, callee GraphNode, cs CallSite) {
-			graph.AddFlow(newCall(caller, callee, cs, ""))
+	var workersWG sync.WaitGroup
+	for start := 0; start < total; start += chunkSize {
+		end := start + chunkSize
+		if end > total {
+			end = total
 		}
 
-		top := fn(a.CallSite)
-		top.Owners = a.DataOwners()
-		graph.AddNode(top)
+		workersWG.Add(1)
+		go func(batch []PrivAccess) {
+			defer workersWG.Done()
 
-		g, err := trace.graph(top, results.Traces, fn, call)
-		if err != nil {
-			return CallGraph{}, err
-		}
+			local := NewCallGraph()
+			sinks := make(strset)
 
-		graph.Union(g)
+			for _, a := range batch {
+				if err := ctx.Err(); err != nil {
+					resultsCh <- accessGraph{err: err}
+					return
+				}
+
+				trace := results.Traces[a.Trace]
+
+				fn := func(cs CallSite) GraphNode {
+					return newTraceNode(cs, "")
+				}
+
+				call := func(caller GraphNode, callee GraphNode, cs CallSite) {
+					local.AddCall(newCall(caller, callee, cs, ""))
+				}
+
+				flow := func(caller GraphNode, callee GraphNode, cs CallSite) {
+					local.AddFlow(newCall(caller, callee, cs, ""))
+				}
+
+				top := fn(a.CallSite)
+				top.Owners = a.DataOwners()
+				local.AddNode(top)
+				sinks.Add(top.Name)
+
+				g, err := trace.graph(top, results.Traces, fn, call)
+				if err != nil {
+					resultsCh <- accessGraph{err: err}
+					return
+				}
+				local.Union(g)
+
+				for _, source := range a.Sources {
+					srcTrace := results.Traces[source.Trace]
+					g, err := srcTrace.graph(top, results.Traces, fn, flow)
+					if err != nil {
+						resultsCh <- accessGraph{err: err}
+						return
+					}
+					local.Union(g)
+				}
 
-		for _, source := range a.Sources {
-			trace := results.Traces[source.Trace]
-			g, err := trace.graph(top, results.Traces, fn, flow)
-			if err != nil {
-				return CallGraph{}, err
+				reportAccess()
 			}
 
-			graph.Union(g)
-		}
+			resultsCh <- accessGraph{graph: local, sinks: sinks}
+		}(accesses[start:end])
+	}
+
+	graph := NewCallGraph()
+	sinks := make(strset)
+
+	var mergeWG sync.WaitGroup
+	var firstErr error
 
-		count++
-		if count%chunk == 0 {
-			go progress(
-				fmt.Sprintf("Processed %d/%d accesses",
-					count, total))
+	mergeWG.Add(1)
+	go func() {
+		defer mergeWG.Done()
+
+		for r := range resultsCh {
+			if r.err != nil {
+				if firstErr == nil {
+					firstErr = r.err
+				}
+				continue
+			}
+
+			graph.UnionAll(r.graph)
+			sinks = sinks.Union(r.sinks)
 		}
+	}()
+
+	workersWG.Wait()
+	close(resultsCh)
+	mergeWG.Wait()
+
+	if firstErr != nil {
+		return CallGraph{}, firstErr
 	}
 
-	return graph, nil
+	// As in VulnGraph, compute the final graph as a fixed-point slice
+	// intersection between entry points and access sinks so recursive
+	// call paths aren't dropped.
+	return graph.SliceIntersect(graph.roots, sinks), nil
 }
 
 //
@@ -0,0 +1,16 @@
+package soaap
+
+import "strings"
+
+//
+// IndirectCallMarker is the function name SOAAP emits at a CallSite when
+// the actual target of a call couldn't be statically resolved (a call
+// through a function pointer or virtual dispatch).
+//
+const IndirectCallMarker = "<indirect>"
+
+// isIndirectCallSite reports whether cs represents an unresolved indirect
+// call rather than a call to a known function.
+func isIndirectCallSite(cs CallSite) bool {
+	return strings.Contains(cs.Function, IndirectCallMarker)
+}
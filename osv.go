@@ -0,0 +1,104 @@
+package soaap
+
+import (
+	"context"
+	"time"
+)
+
+//
+// An entry from the OSV (Open Source Vulnerability) database, or any source
+// that speaks its schema (https://ossf.github.io/osv-schema/), describing a
+// single vulnerability.
+//
+type OSVEntry struct {
+	ID      string
+	Summary string
+	Details string
+
+	Aliases []string
+
+	Affected   []OSVAffected
+	References []OSVReference
+
+	Published time.Time
+	Modified  time.Time
+}
+
+//
+// A package/version range affected by an OSVEntry.
+//
+type OSVAffected struct {
+	Package struct {
+		Ecosystem string
+		Name      string
+	}
+
+	Ranges []struct {
+		Type   string
+		Events []map[string]string
+	}
+}
+
+//
+// A reference URL attached to an OSVEntry (advisory, fix commit, report, ...).
+//
+type OSVReference struct {
+	Type string
+	URL  string
+}
+
+//
+// OSVClient looks up a single OSV (or OSV-shaped) entry by ID.
+//
+// Implementations might talk to the OSV HTTP API, read a local mirror
+// directory of `GHSA-*.json`/`CVE-*.json` files, or (in tests) serve a
+// canned set of entries.
+//
+type OSVClient interface {
+	Lookup(id string) (*OSVEntry, error)
+}
+
+//
+// EnrichOSV looks up every CVE referenced by r's Vulnerabilities via client
+// and attaches the results to each Vuln's OSV field. A CVE with no matching
+// entry (client.Lookup returns a nil entry and no error) is silently
+// skipped; a failed lookup aborts enrichment entirely so that callers can
+// retry or fall back.
+//
+// Enriched entries are plain fields on Vuln, so they're persisted through
+// the existing gob Save/LoadResults path without further work.
+//
+func (r *Results) EnrichOSV(ctx context.Context, client OSVClient) error {
+	// Looking up the same CVE for multiple vulnerabilities is common (the
+	// same CVE can show up at several call sites), so cache by ID.
+	cache := make(map[string]*OSVEntry)
+
+	for i := range r.Vulnerabilities {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		v := &r.Vulnerabilities[i]
+		entries := make([]OSVEntry, 0, len(v.CVE))
+
+		for _, cve := range v.CVE {
+			entry, ok := cache[cve.ID]
+			if !ok {
+				var err error
+				entry, err = client.Lookup(cve.ID)
+				if err != nil {
+					return err
+				}
+				cache[cve.ID] = entry
+			}
+
+			if entry != nil {
+				entries = append(entries, *entry)
+			}
+		}
+
+		v.OSV = entries
+	}
+
+	return nil
+}
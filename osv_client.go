@@ -0,0 +1,91 @@
+package soaap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//
+// OSVHTTPClient looks up OSV entries from an OSV-schema HTTP API (the
+// public instance is https://api.osv.dev/v1/vulns), fetching
+// "<BaseURL>/<id>" and decoding the body as a single OSVEntry. A 404
+// response is treated as "no such entry" (Lookup returns a nil entry and
+// no error), matching EnrichOSV's doc comment.
+//
+type OSVHTTPClient struct {
+	// BaseURL is the API's vuln-by-ID endpoint, with no trailing slash,
+	// e.g. "https://api.osv.dev/v1/vulns".
+	BaseURL string
+
+	// Timeout bounds each request; zero means 30 seconds.
+	Timeout time.Duration
+}
+
+func (c *OSVHTTPClient) Lookup(id string) (*OSVEntry, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	resp, err := (&http.Client{Timeout: timeout}).Get(c.BaseURL + "/" + url.PathEscape(id))
+	if err != nil {
+		return nil, fmt.Errorf("looking up %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("looking up %s: %s", id, resp.Status)
+	}
+
+	var entry OSVEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entry); err != nil {
+		return nil, fmt.Errorf("decoding OSV entry for %s: %w", id, err)
+	}
+
+	return &entry, nil
+}
+
+//
+// OSVDirClient looks up OSV entries from a local mirror directory, as
+// published by the OSV project's "all vulnerabilities" export: one
+// "<id>.json" file per entry (e.g. GHSA-xxxx-xxxx-xxxx.json,
+// CVE-2024-xxxxx.json), each holding a single OSVEntry.
+//
+type OSVDirClient struct {
+	Dir string
+}
+
+func (c *OSVDirClient) Lookup(id string) (*OSVEntry, error) {
+	// id comes from a Vuln's CVE.ID, which originates in the analyzed
+	// project's own SOAAP annotations rather than anything this module
+	// controls - reject anything that could walk outside Dir instead of
+	// joining it into a path unchecked.
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return nil, fmt.Errorf("invalid OSV id %q", id)
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.Dir, id+".json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry OSVEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", id, err)
+	}
+
+	return &entry, nil
+}
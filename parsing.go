@@ -1,9 +1,11 @@
 package soaap
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -15,115 +17,242 @@ import (
 // The `progress` callback will be notified when major events occur
 // (top-level JSON parsing begins/ends, traces are parsed, etc.).
 //
-func ParseJSON(f *os.File, progress func(string)) (Results, error) {
-	decoder := json.NewDecoder(f)
-	var top map[string]map[string]json.RawMessage
+func ParseJSON(ctx context.Context, f *os.File, progress ProgressReporter) (Results, error) {
+	total := int64(0)
+	if info, err := f.Stat(); err == nil {
+		total = info.Size()
+	}
+
+	progress.Start(total, fmt.Sprintf("Loading %s", f.Name()))
+	defer progress.Finish()
+
+	return ParseJSONStream(ctx, f, progress)
+}
+
+//
+// Parse SOAAP JSON output read from an arbitrary io.Reader, such as a pipe
+// fed directly from SOAAP rather than a temporary file.
+//
+// Unlike the original implementation, this decodes the top-level object a
+// key at a time via json.Decoder rather than buffering the whole document
+// (and every trace's raw JSON) in memory up front, which matters for SOAAP
+// output that can run into the hundreds of megabytes. `Traces` grows lazily
+// as trace indices are encountered, so no up-front count is required.
+//
+// `progress` is Add'd the number of bytes consumed so far, derived from
+// decoder.InputOffset(), each time a top-level object finishes parsing.
+// `ctx` is checked once per top-level key, so a cancellation (e.g. -watch
+// noticing a new file revision mid-parse) stops the decode promptly rather
+// than running the previous revision to completion.
+//
+func ParseJSONStream(ctx context.Context, r io.Reader, progress ProgressReporter) (Results, error) {
+	decoder := json.NewDecoder(r)
+
+	if err := expectDelim(decoder, '{'); err != nil {
+		return Results{}, err
+	}
 
-	go progress(fmt.Sprintf("Loading %s", f.Name()))
-	err := decoder.Decode(&top)
+	key, err := decoder.Token()
 	if err != nil {
 		return Results{}, err
 	}
-	raw := top["soaap"]
+	if name, ok := key.(string); !ok || name != "soaap" {
+		return Results{}, fmt.Errorf("expected \"soaap\" key, got %v", key)
+	}
+
+	if err := expectDelim(decoder, '{'); err != nil {
+		return Results{}, err
+	}
 
 	var soaap Results
+	soaap.Traces = make([]CallTrace, 0)
 
-	maxTraceSize := len(raw)
-	soaap.Traces = make([]CallTrace, maxTraceSize)
+	var lastOffset int64
 
-	parsed := 0
+	for decoder.More() {
+		if err := ctx.Err(); err != nil {
+			return Results{}, err
+		}
 
-	// Once SOAAP issue #28 is resolved, we should be able to replace this
-	// loop (as well as the `parseTrace` function) with a single call to
-	// decoder.Decode().
-	for k, v := range raw {
-		switch k {
-		case "access_origin_warning":
-			// TODO
+		key, err := decoder.Token()
+		if err != nil {
+			return Results{}, err
+		}
 
-		case "cap_rights_warning":
-			// TODO
+		k, ok := key.(string)
+		if !ok {
+			return Results{}, fmt.Errorf("expected a string key, got %v", key)
+		}
 
-		case "classified_warning":
-			// TODO
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return Results{}, err
+		}
 
-		case "global_access_warning":
-			// TODO
+		if err := soaap.parseEntry(k, raw); err != nil {
+			return Results{}, err
+		}
 
-		case "global_lost_update":
-			// TODO
+		if offset := decoder.InputOffset(); offset != lastOffset {
+			progress.Add(offset - lastOffset)
+			lastOffset = offset
+		}
+	}
 
-		case "private_access":
-			json.Unmarshal(v, &soaap.PrivateAccess)
-			for i, vuln := range soaap.PrivateAccess {
-				num, err := traceNumber(vuln.TraceName)
-				if err != nil {
-					return Results{}, err
-				}
+	if err := validateTraceChains(soaap.Traces); err != nil {
+		return Results{}, err
+	}
 
-				soaap.PrivateAccess[i].Trace = num
+	return soaap, nil
+}
 
-				// Build a slice of *useful* sources (i.e., those with traces)
-				sources := make([]DataSource, 0)
+//
+// Handle one key/value pair from the top-level "soaap" object, as produced
+// by ParseJSONStream.
+//
+// Once SOAAP issue #28 is resolved, we should be able to replace this (as
+// well as `parseTrace`) with a single call to decoder.Decode().
+//
+func (soaap *Results) parseEntry(k string, v json.RawMessage) error {
+	switch k {
+	case "access_origin_warning":
+		// TODO
 
-				for _, source := range vuln.Sources {
-					if source.TraceRef != "" {
-						num, err := traceNumber(source.TraceRef)
-						if err != nil {
-							return Results{}, err
-						}
-						source.Trace = num
-						sources = append(sources, source)
-					}
-				}
+	case "cap_rights_warning":
+		// TODO
 
-				soaap.PrivateAccess[i].Sources = sources
-			}
+	case "classified_warning":
+		// TODO
 
-		case "private_leak":
-			// TODO
+	case "global_access_warning":
+		// TODO
 
-		case "privileged_call":
-			// TODO
+	case "global_lost_update":
+		// TODO
 
-		case "sandboxed_func":
-			// TODO
+	case "private_access":
+		json.Unmarshal(v, &soaap.PrivateAccess)
+		for i, vuln := range soaap.PrivateAccess {
+			num, err := traceNumber(vuln.TraceName)
+			if err != nil {
+				return err
+			}
 
-		case "syscall_warning":
-			// TODO
+			soaap.PrivateAccess[i].Trace = num
 
-		case "vulnerability_warning":
-			json.Unmarshal(v, &soaap.Vulnerabilities)
-			for i, vuln := range soaap.Vulnerabilities {
-				num, err := traceNumber(vuln.TraceName)
-				if err != nil {
-					return Results{}, err
-				}
+			// Build a slice of *useful* sources (i.e., those with traces)
+			sources := make([]DataSource, 0)
 
-				soaap.Vulnerabilities[i].Trace = num
+			for _, source := range vuln.Sources {
+				if source.TraceRef != "" {
+					num, err := traceNumber(source.TraceRef)
+					if err != nil {
+						return err
+					}
+					source.Trace = num
+					sources = append(sources, source)
+				}
 			}
 
-		default:
-			index, err := traceNumber(k)
-			if err != nil {
-				return soaap, errors.New(k + " is not a trace")
-			}
+			soaap.PrivateAccess[i].Sources = sources
+		}
+
+	case "private_leak":
+		// TODO
 
-			err = parseTrace(v, soaap.Traces, index)
+	case "privileged_call":
+		// TODO
+
+	case "sandboxed_func":
+		// TODO
+
+	case "syscall_warning":
+		// TODO
+
+	case "vulnerability_warning":
+		json.Unmarshal(v, &soaap.Vulnerabilities)
+		for i, vuln := range soaap.Vulnerabilities {
+			num, err := traceNumber(vuln.TraceName)
 			if err != nil {
-				return soaap, err
+				return err
 			}
+
+			soaap.Vulnerabilities[i].Trace = num
 		}
 
-		parsed += 1
-		if parsed%10000 == 0 {
-			go progress(fmt.Sprintf("Parsed %d traces", parsed))
+	default:
+		index, err := traceNumber(k)
+		if err != nil {
+			return errors.New(k + " is not a trace")
+		}
+
+		ensureTraceCapacity(&soaap.Traces, index)
+
+		err = parseTrace(v, soaap.Traces, index)
+		if err != nil {
+			return err
 		}
 	}
 
-	progress(fmt.Sprintf("Finished parsing %s.", f.Name()))
+	return nil
+}
 
-	return soaap, nil
+//
+// Reject `Next` chains that are too deep or that cycle back on themselves,
+// rather than letting callers discover the problem the first time they
+// walk a trace (see CallTrace.ForeachN).
+//
+func validateTraceChains(traces []CallTrace) error {
+	for i, t := range traces {
+		err := t.ForeachN(traces, DefaultMaxTraceDepth, func(CallSite) {})
+		if err != nil {
+			return fmt.Errorf("trace %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Grow `traces` (if necessary) so that index `i` is valid, doubling
+// capacity rather than growing to exactly `i+1` each time: real SOAAP
+// output numbers traces "!trace0", "!trace1", ... in increasing order, so
+// growing to the exact size needed would reallocate and copy the whole
+// slice on every single trace - O(n^2) for exactly the large-file case
+// this streaming parser exists to handle well.
+func ensureTraceCapacity(traces *[]CallTrace, i int) {
+	if i < len(*traces) {
+		return
+	}
+
+	if i < cap(*traces) {
+		*traces = (*traces)[:i+1]
+		return
+	}
+
+	newCap := cap(*traces) * 2
+	if newCap <= i {
+		newCap = i + 1
+	}
+
+	grown := make([]CallTrace, i+1, newCap)
+	copy(grown, *traces)
+	*traces = grown
+}
+
+// Read the next token from `decoder`, returning an error unless it's the
+// delimiter `d` (e.g. '{' or '[').
+func expectDelim(decoder *json.Decoder, d json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok || delim != d {
+		return fmt.Errorf("expected '%c', got %v", d, token)
+	}
+
+	return nil
 }
 
 //
@@ -0,0 +1,474 @@
+package soaap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+//
+// A declarative alternative to shell-piping soaap-graph/soaap-combine and
+// hand-tracking intermediate .graph files: a PipelineConfig names a DAG of
+// stages, each producing a CallGraph from some combination of a fresh
+// input, a combination of earlier stages, and a transform, optionally
+// writing it out. RunPipeline executes the DAG in topological order so a
+// stage's dependencies (named in Combine.With or Transform.From) are always
+// already built by the time it runs.
+//
+type PipelineConfig struct {
+	Stages []PipelineStage
+}
+
+//
+// One node of the pipeline DAG. Exactly one of Input, Combine or Transform
+// should be set; Output is optional on every stage (a stage can be written
+// out and also feed later stages).
+//
+type PipelineStage struct {
+	Name string
+
+	Input *PipelineInput
+
+	Combine *PipelineCombine
+
+	Transform *PipelineTransform
+
+	Output *PipelineOutput
+}
+
+// PipelineInput loads a fresh graph, either directly (GraphInput) or by
+// extracting and combining one or more analyses out of SOAAP results.
+type PipelineInput struct {
+	// Path or URL (see OpenInput) to read from.
+	Path string
+
+	// GraphInput treats Path as a previously-saved graph rather than raw
+	// SOAAP results.
+	GraphInput bool
+
+	// Analyses are applied in order via ApplyAnalysis, as soaap-graph's
+	// -analyses flag does, and only make sense when GraphInput is false.
+	Analyses []string
+
+	IntersectionDepth int
+}
+
+// PipelineCombine merges one or more earlier stages (named in With) into
+// the first stage named there, using the same operations soaap-combine
+// supports.
+type PipelineCombine struct {
+	// Op is "union", "intersection" or "addintersecting".
+	Op string
+
+	With []string
+
+	IntersectionDepth int
+}
+
+// PipelineTransform derives a new graph from a single earlier stage (named
+// in From).
+type PipelineTransform struct {
+	From string
+
+	Simplify bool
+
+	// Focus keeps only leaf nodes matching this regex (plus ancestors), the
+	// same as Filter(g, "+"+Focus).
+	Focus string
+}
+
+// PipelineOutput writes a stage's graph out, either as Dot (GroupBy, if
+// set, groups nodes the way WriteDot's groupBy parameter does) or via one of
+// the GraphCodec encodings.
+type PipelineOutput struct {
+	// Format is "dot", "gob", "json" or "proto".
+	Format string
+
+	Path string
+
+	GroupBy string
+}
+
+//
+// ParsePipelineConfig parses a pipeline config document (see PipelineConfig)
+// out of YAML, via this module's stdlib-only YAML subset (parseYAMLLite).
+//
+func ParsePipelineConfig(data []byte) (PipelineConfig, error) {
+	tree, err := parseYAMLLite(data)
+	if err != nil {
+		return PipelineConfig{}, err
+	}
+
+	root, ok := tree.(map[string]interface{})
+	if !ok {
+		return PipelineConfig{}, fmt.Errorf("pipeline config must be a mapping at the top level")
+	}
+
+	rawStages, ok := root["stages"].([]interface{})
+	if !ok {
+		return PipelineConfig{}, fmt.Errorf("pipeline config must have a \"stages\" list")
+	}
+
+	cfg := PipelineConfig{Stages: make([]PipelineStage, 0, len(rawStages))}
+
+	for i, raw := range rawStages {
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return PipelineConfig{}, fmt.Errorf("stage %d: expected a mapping", i)
+		}
+
+		stage, err := parsePipelineStage(m)
+		if err != nil {
+			return PipelineConfig{}, fmt.Errorf("stage %d: %w", i, err)
+		}
+
+		cfg.Stages = append(cfg.Stages, stage)
+	}
+
+	return cfg, nil
+}
+
+func parsePipelineStage(m map[string]interface{}) (PipelineStage, error) {
+	name, _ := yamlString(m, "name")
+	if name == "" {
+		return PipelineStage{}, fmt.Errorf("missing \"name\"")
+	}
+
+	stage := PipelineStage{Name: name}
+
+	if path, ok := yamlString(m, "input"); ok {
+		depth, _ := yamlInt(m, "intersection-depth")
+		if depth == 0 {
+			depth = 3
+		}
+
+		stage.Input = &PipelineInput{
+			Path:              path,
+			GraphInput:        yamlBool(m, "graph-input"),
+			Analyses:          yamlStringSlice(m, "analyses"),
+			IntersectionDepth: depth,
+		}
+	}
+
+	if op, ok := yamlString(m, "combine"); ok {
+		depth, _ := yamlInt(m, "intersection-depth")
+		if depth == 0 {
+			depth = 3
+		}
+
+		with := yamlStringSlice(m, "with")
+		if len(with) < 2 {
+			return PipelineStage{}, fmt.Errorf("\"combine\" needs at least 2 stages in \"with\"")
+		}
+
+		stage.Combine = &PipelineCombine{Op: op, With: with, IntersectionDepth: depth}
+	}
+
+	if from, ok := yamlString(m, "from"); ok {
+		focus, _ := yamlString(m, "focus")
+
+		stage.Transform = &PipelineTransform{
+			From:     from,
+			Simplify: yamlBool(m, "simplify"),
+			Focus:    focus,
+		}
+	}
+
+	if stage.Input == nil && stage.Combine == nil && stage.Transform == nil {
+		return PipelineStage{}, fmt.Errorf("stage %q has none of \"input\", \"combine\" or \"from\"", name)
+	}
+
+	if rawOut, ok := m["output"]; ok {
+		out, ok := rawOut.(map[string]interface{})
+		if !ok {
+			return PipelineStage{}, fmt.Errorf("stage %q: \"output\" must be a mapping", name)
+		}
+
+		format, _ := yamlString(out, "format")
+		path, _ := yamlString(out, "path")
+		if path == "" {
+			path = "-"
+		}
+		groupBy, _ := yamlString(out, "group-by")
+
+		stage.Output = &PipelineOutput{Format: format, Path: path, GroupBy: groupBy}
+	}
+
+	return stage, nil
+}
+
+func yamlString(m map[string]interface{}, key string) (string, bool) {
+	s, ok := m[key].(string)
+	return s, ok
+}
+
+func yamlBool(m map[string]interface{}, key string) bool {
+	b, _ := m[key].(bool)
+	return b
+}
+
+func yamlInt(m map[string]interface{}, key string) (int, bool) {
+	i, ok := m[key].(int)
+	return i, ok
+}
+
+func yamlStringSlice(m map[string]interface{}, key string) []string {
+	raw, ok := m[key].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	out := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+
+	return out
+}
+
+//
+// RunPipeline executes every stage of cfg, in topological order (a stage's
+// dependencies - PipelineCombine.With, PipelineTransform.From - always run
+// before it does), and returns every named stage's resulting CallGraph.
+// `progress` is given to each stage's own loading/combining calls, the same
+// reporter soaap-graph and soaap-combine thread through for a single
+// invocation. `ctx` is checked between stages, and threaded into each
+// stage's own LoadResults/ApplyAnalysis calls, so -watch mode (see watch.go)
+// can cancel a run that a newer file revision has already made stale.
+//
+func RunPipeline(ctx context.Context, cfg PipelineConfig, progress ProgressReporter) (map[string]CallGraph, error) {
+	order, err := topoSortStages(cfg.Stages)
+	if err != nil {
+		return nil, err
+	}
+
+	graphs := make(map[string]CallGraph, len(cfg.Stages))
+
+	for _, stage := range order {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		graph, err := runPipelineStage(ctx, stage, graphs, progress)
+		if err != nil {
+			return nil, fmt.Errorf("stage %q: %w", stage.Name, err)
+		}
+
+		graphs[stage.Name] = graph
+
+		if stage.Output != nil {
+			if err := writePipelineOutput(stage.Output, graph); err != nil {
+				return nil, fmt.Errorf("stage %q: writing output: %w", stage.Name, err)
+			}
+		}
+	}
+
+	return graphs, nil
+}
+
+func runPipelineStage(ctx context.Context, stage PipelineStage, graphs map[string]CallGraph, progress ProgressReporter) (CallGraph, error) {
+	switch {
+	case stage.Input != nil:
+		return runPipelineInput(ctx, stage.Input, progress)
+
+	case stage.Combine != nil:
+		return runPipelineCombine(stage.Combine, graphs, progress)
+
+	case stage.Transform != nil:
+		return runPipelineTransform(stage.Transform, graphs)
+
+	default:
+		return CallGraph{}, fmt.Errorf("stage has no input, combine or transform")
+	}
+}
+
+func runPipelineInput(ctx context.Context, in *PipelineInput, progress ProgressReporter) (CallGraph, error) {
+	f, err := OpenInput(in.Path)
+	if err != nil {
+		return CallGraph{}, err
+	}
+	defer f.Close()
+
+	if in.GraphInput {
+		return CodecForFile(f.Name()).Decode(f)
+	}
+
+	results, err := LoadResults(ctx, f, progress)
+	if err != nil {
+		return CallGraph{}, err
+	}
+
+	graph := NewCallGraph()
+
+	analyses := in.Analyses
+	if len(analyses) == 0 {
+		analyses = []string{"vuln"}
+	}
+
+	for _, analysis := range analyses {
+		graph, err = ApplyAnalysis(ctx, analysis, &graph, &results, in.IntersectionDepth, progress)
+		if err != nil {
+			return CallGraph{}, err
+		}
+	}
+
+	return graph, nil
+}
+
+func runPipelineCombine(c *PipelineCombine, graphs map[string]CallGraph, progress ProgressReporter) (CallGraph, error) {
+	base, ok := graphs[c.With[0]]
+	if !ok {
+		return CallGraph{}, fmt.Errorf("unknown stage %q in \"with\"", c.With[0])
+	}
+
+	for _, name := range c.With[1:] {
+		g, ok := graphs[name]
+		if !ok {
+			return CallGraph{}, fmt.Errorf("unknown stage %q in \"with\"", name)
+		}
+
+		var err error
+		switch c.Op {
+		case "union":
+			err = base.UnionWithProgress(g, progress)
+
+		case "intersection":
+			base, err = base.IntersectWithProgress(g, c.IntersectionDepth, true, progress)
+
+		case "addintersecting":
+			err = base.AddIntersectingWithProgress(g, c.IntersectionDepth, progress)
+
+		default:
+			return CallGraph{}, fmt.Errorf("unknown combining operation %q", c.Op)
+		}
+
+		if err != nil {
+			return CallGraph{}, err
+		}
+	}
+
+	return base, nil
+}
+
+func runPipelineTransform(t *PipelineTransform, graphs map[string]CallGraph) (CallGraph, error) {
+	graph, ok := graphs[t.From]
+	if !ok {
+		return CallGraph{}, fmt.Errorf("unknown stage %q in \"from\"", t.From)
+	}
+
+	if t.Focus != "" {
+		var err error
+		graph, err = Filter(graph, "+"+t.Focus)
+		if err != nil {
+			return CallGraph{}, err
+		}
+	}
+
+	if t.Simplify {
+		graph = graph.Simplified()
+	}
+
+	return graph, nil
+}
+
+func writePipelineOutput(out *PipelineOutput, graph CallGraph) error {
+	var w io.WriteCloser
+	if out.Path == "-" {
+		w = nopCloser{os.Stdout}
+	} else {
+		f, err := os.Create(out.Path)
+		if err != nil {
+			return err
+		}
+		w = f
+	}
+	defer w.Close()
+
+	switch out.Format {
+	case "", "dot":
+		return graph.WriteDot(w, out.GroupBy)
+	case "gob":
+		return GobCodec{}.Encode(w, graph)
+	case "json":
+		return JSONCodec{}.Encode(w, graph)
+	case "proto":
+		return ProtoCodec{}.Encode(w, graph)
+	default:
+		return fmt.Errorf("unknown output format %q (want dot, gob, json or proto)", out.Format)
+	}
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// topoSortStages orders stages so that every dependency (PipelineCombine.With,
+// PipelineTransform.From) comes before the stage that names it, erroring out
+// on an unknown dependency or a cycle.
+func topoSortStages(stages []PipelineStage) ([]PipelineStage, error) {
+	byName := make(map[string]PipelineStage, len(stages))
+	for _, s := range stages {
+		if _, dup := byName[s.Name]; dup {
+			return nil, fmt.Errorf("duplicate stage name %q", s.Name)
+		}
+		byName[s.Name] = s
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+
+	state := make(map[string]int, len(stages))
+	var order []PipelineStage
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle at stage %q", name)
+		}
+
+		stage, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("unknown stage %q", name)
+		}
+
+		state[name] = visiting
+
+		for _, dep := range stageDeps(stage) {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+
+		state[name] = done
+		order = append(order, stage)
+		return nil
+	}
+
+	for _, s := range stages {
+		if err := visit(s.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+func stageDeps(stage PipelineStage) []string {
+	switch {
+	case stage.Combine != nil:
+		return stage.Combine.With
+	case stage.Transform != nil:
+		return []string{stage.Transform.From}
+	default:
+		return nil
+	}
+}
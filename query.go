@@ -0,0 +1,162 @@
+package soaap
+
+//
+// Digraph-style query primitives over a CallGraph, modeled on
+// golang.org/x/tools/cmd/digraph's commands (nodes, degree, preds/succs,
+// forward/reverse, somepath, allpaths, sccs/scc, focus, transpose), but
+// walking calls and data flows together the way the rest of this package
+// does (see GraphNode.AllInputs/AllOutputs). soaap-query is the CLI built
+// on top of these.
+//
+
+// NodeNames returns the name of every node in cg.
+func (cg CallGraph) NodeNames() []string {
+	names := make([]string, 0, len(cg.nodes))
+	for n := range cg.nodes {
+		names = append(names, n)
+	}
+	return names
+}
+
+// Degree returns the in-degree and out-degree of node `name` (calls and
+// flows combined).
+func (cg CallGraph) Degree(name string) (in, out int) {
+	n := cg.nodes[name]
+	return len(n.CallsIn) + len(n.FlowsIn), len(n.CallsOut) + len(n.FlowsOut)
+}
+
+// Preds returns the immediate predecessors (callers and data sources) of
+// node `name`.
+func (cg CallGraph) Preds(name string) strset {
+	return cg.nodes[name].AllInputs()
+}
+
+// Succs returns the immediate successors (callees and data sinks) of node
+// `name`.
+func (cg CallGraph) Succs(name string) strset {
+	return cg.nodes[name].AllOutputs()
+}
+
+// Forward returns every node transitively reachable from `seeds`
+// (inclusive), by calls or data flows.
+func (cg CallGraph) Forward(seeds strset) strset {
+	return cg.expandToFixedPoint(seeds, GraphNode.AllOutputs)
+}
+
+// Reverse returns every node that can transitively reach `seeds`
+// (inclusive).
+func (cg CallGraph) Reverse(seeds strset) strset {
+	return cg.expandToFixedPoint(seeds, GraphNode.AllInputs)
+}
+
+// SomePath returns some path (as a slice of node names, `from` to `to`
+// inclusive) from `from` to `to`, found by a BFS parent-pointer walk, or
+// nil if `to` isn't reachable from `from`.
+func (cg CallGraph) SomePath(from, to string) []string {
+	if from == to {
+		return []string{from}
+	}
+
+	parent := map[string]string{from: from}
+	queue := []string{from}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		for n := range cg.nodes[cur].AllOutputs() {
+			if _, seen := parent[n]; seen {
+				continue
+			}
+			parent[n] = cur
+
+			if n == to {
+				return buildPath(parent, from, to)
+			}
+			queue = append(queue, n)
+		}
+	}
+
+	return nil
+}
+
+// buildPath walks `parent` back from `to` to `from`, returning the path in
+// from-to order.
+func buildPath(parent map[string]string, from, to string) []string {
+	path := []string{to}
+
+	for cur := to; cur != from; {
+		cur = parent[cur]
+		path = append(path, cur)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	return path
+}
+
+// AllPaths returns the subgraph of cg containing every node that lies on
+// some path from `from` to `to`: the intersection of nodes reachable
+// forward from `from` and reachable backward from `to`.
+func (cg CallGraph) AllPaths(from, to string) CallGraph {
+	forward := cg.Forward(singleton(from))
+	reverse := cg.Reverse(singleton(to))
+
+	return cg.filterTo(forward.Intersection(reverse))
+}
+
+// SCCOf returns the strongly connected component containing `name`
+// (including `name` itself, even when it's alone), or nil if `name` isn't
+// in the graph.
+func (cg CallGraph) SCCOf(name string) []string {
+	for _, scc := range cg.StronglyConnectedComponents() {
+		for _, m := range scc {
+			if m == name {
+				return scc
+			}
+		}
+	}
+
+	return nil
+}
+
+// Focus returns the subgraph of cg reachable to or from `name`: everything
+// that can reach it, plus everything it can reach.
+func (cg CallGraph) Focus(name string) CallGraph {
+	seed := singleton(name)
+	return cg.filterTo(cg.Forward(seed).Union(cg.Reverse(seed)))
+}
+
+// Transpose returns cg with every call and flow edge reversed.
+func (cg CallGraph) Transpose() CallGraph {
+	result := NewCallGraph()
+
+	for _, node := range cg.nodes {
+		n := node
+		n.CallsIn, n.CallsOut = nil, nil
+		n.FlowsIn, n.FlowsOut = nil, nil
+		result.AddNode(n)
+	}
+
+	for call, weight := range cg.calls {
+		result.AddCalls(reverseCall(call), weight)
+	}
+
+	for flow, weight := range cg.flows {
+		result.AddFlows(reverseCall(flow), weight)
+	}
+
+	return result
+}
+
+func reverseCall(c Call) Call {
+	return Call{Caller: c.Callee, Callee: c.Caller, CallSite: c.CallSite, Sandbox: c.Sandbox}
+}
+
+func singleton(name string) strset {
+	s := make(strset)
+	s.Add(name)
+	return s
+}
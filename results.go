@@ -1,12 +1,28 @@
 package soaap
 
 import (
+	"context"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 )
 
+// DefaultMaxTraceDepth bounds the number of `Next` hops that Foreach/ForeachN
+// will follow before giving up on a trace chain. It's large enough to cover
+// any legitimate SOAAP output, while still protecting against runaway
+// recursion on a malformed or malicious results file.
+const DefaultMaxTraceDepth = 10000
+
+// ErrTraceCycle is returned (via errors.Is) when a chain of `Next` references
+// among CallTraces forms a cycle.
+var ErrTraceCycle = errors.New("cycle detected while walking call trace")
+
+// ErrTraceDepthExceeded is returned (via errors.Is) when a chain of `Next`
+// references is longer than the requested maximum depth.
+var ErrTraceDepthExceeded = errors.New("maximum trace depth exceeded")
+
 //
 // The results of running SOAAP on an application.
 //
@@ -23,25 +39,36 @@ type Results struct {
 //
 // Load SOAAP results from an os.File (either binary- or JSON-encoded).
 //
-func LoadResults(f *os.File, report func(string)) (Results, error) {
+// ctx is checked for cancellation while parsing JSON (see ParseJSONStream);
+// a gob-encoded file decodes in one shot and so is only checked up front,
+// since encoding/gob offers no way to interrupt a Decode already in flight.
+//
+func LoadResults(ctx context.Context, f *os.File, progress ProgressReporter) (Results, error) {
+	if err := ctx.Err(); err != nil {
+		return Results{}, err
+	}
+
 	if strings.HasSuffix(f.Name(), ".gob") {
+		progress.Start(0, fmt.Sprintf("Loading %s", f.Name()))
+		defer progress.Finish()
+
 		var results Results
 		err := gob.NewDecoder(f).Decode(&results)
 
 		return results, err
 	}
 
-	return ParseJSON(f, report)
+	return ParseJSON(ctx, f, progress)
 }
 
-func (r Results) ExtractGraph(analysis string, progress func(string)) (CallGraph, error) {
+func (r Results) ExtractGraph(ctx context.Context, analysis string, progress ProgressReporter) (CallGraph, error) {
 	fn, ok := graphExtractors[analysis]
 	if !ok {
 		return CallGraph{},
 			fmt.Errorf("unknown analysis: '%s'", analysis)
 	}
 
-	return fn(r, progress)
+	return fn(ctx, r, progress)
 }
 
 func (r Results) Save(f *os.File) error {
@@ -58,6 +85,10 @@ type Vuln struct {
 	Type       string
 	CVE        []CVE
 	Restricted bool `json:"restricted_rights"`
+
+	// OSV entries fetched for this vulnerability's CVE IDs, if any.
+	// Populated by Results.EnrichOSV; empty until then.
+	OSV []OSVEntry
 }
 
 func (v Vuln) CVEs() strset {
@@ -125,28 +156,58 @@ type CallTrace struct {
 // warning location and moving to the root, passing through other traces
 // contained in `traces` as necessary.
 //
+// This is a convenience wrapper around ForeachN using DefaultMaxTraceDepth.
+//
 // Example:
 // ```go
 // trace.Foreach(traces, func(cs CallSite) { fmt.Println(cs.Function) })
 // ```
 //
 func (t CallTrace) Foreach(traces []CallTrace, fn func(CallSite)) error {
-	for _, cs := range t.CallSites {
-		if cs.Location.File != "" {
-			fn(cs)
+	return t.ForeachN(traces, DefaultMaxTraceDepth, fn)
+}
+
+//
+// Apply a function to every CallSite in a trace, as Foreach does, but walk
+// the `Next` chain iteratively rather than recursively, bailing out with
+// ErrTraceDepthExceeded after `maxDepth` traces and with ErrTraceCycle if a
+// `Next` reference leads back to a trace we've already visited.
+//
+// A malformed (or malicious) SOAAP results file can chain `Next` references
+// into a very deep or cyclic walk; an explicit worklist avoids exhausting
+// the goroutine stack the way a naive recursive walk would.
+//
+func (t CallTrace) ForeachN(traces []CallTrace, maxDepth int, fn func(CallSite)) error {
+	visited := make(map[int]bool)
+	cur := t
+
+	for depth := 0; ; depth++ {
+		if depth >= maxDepth {
+			return fmt.Errorf("%w: exceeded %d traces", ErrTraceDepthExceeded, maxDepth)
+		}
+
+		for _, cs := range cur.CallSites {
+			if cs.Location.File != "" {
+				fn(cs)
+			}
 		}
-	}
 
-	if t.Next >= 0 {
-		if t.Next >= len(traces) {
+		if cur.Next < 0 {
+			return nil
+		}
+
+		if cur.Next >= len(traces) {
 			return fmt.Errorf("trace ID (%d) out of range (have %d traces)",
-				t.Next, len(traces))
+				cur.Next, len(traces))
 		}
 
-		traces[t.Next].Foreach(traces, fn)
-	}
+		if visited[cur.Next] {
+			return fmt.Errorf("%w: trace %d revisited", ErrTraceCycle, cur.Next)
+		}
+		visited[cur.Next] = true
 
-	return nil
+		cur = traces[cur.Next]
+	}
 }
 
 //
@@ -0,0 +1,240 @@
+package soaap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//
+// StronglyConnectedComponents partitions cg's nodes into strongly connected
+// components using Tarjan's algorithm, following both call and data-flow
+// edges (node.Callees() union node.DataSinks()) as the edge source.
+//
+// Simplified and walkChain both give up on cyclic regions of the graph
+// (walkChain stops at any node with more than one caller, which is what the
+// node where a cycle is entered from outside always has), which can hide a
+// CVE that's only reachable through recursion.
+// Condense builds on this to collapse each component into a single node so
+// those regions have somewhere to show up.
+//
+func (cg CallGraph) StronglyConnectedComponents() [][]string {
+	index := 0
+	indices := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := make([]string, 0)
+	sccs := make([][]string, 0)
+
+	for name := range cg.nodes {
+		if _, visited := indices[name]; !visited {
+			cg.strongconnect(name, &index, indices, lowlink, onStack, &stack, &sccs)
+		}
+	}
+
+	return sccs
+}
+
+// strongconnectFrame is one level of strongconnect's would-be call stack:
+// the node being visited and how far through its neighbors it's gotten, so
+// the walk can resume there after pushing a child.
+type strongconnectFrame struct {
+	node      string
+	neighbors []string
+	next      int
+}
+
+// strongconnect runs Tarjan's algorithm from `start` using an explicit work
+// stack rather than native recursion: a CallGraph can have hundreds of
+// thousands of nodes (see CallTrace.ForeachN for the same tradeoff on
+// trace walks), and a recursive implementation would use one goroutine
+// stack frame per node along the deepest path.
+func (cg CallGraph) strongconnect(start string, index *int, indices, lowlink map[string]int,
+	onStack map[string]bool, stack *[]string, sccs *[][]string) {
+
+	visit := func(v string) {
+		indices[v] = *index
+		lowlink[v] = *index
+		*index++
+
+		*stack = append(*stack, v)
+		onStack[v] = true
+	}
+
+	visit(start)
+
+	frames := []*strongconnectFrame{{node: start, neighbors: setKeys(cg.nodes[start].AllOutputs())}}
+
+	for len(frames) > 0 {
+		frame := frames[len(frames)-1]
+		v := frame.node
+
+		if frame.next >= len(frame.neighbors) {
+			frames = frames[:len(frames)-1]
+
+			if lowlink[v] == indices[v] {
+				scc := make([]string, 0)
+
+				for {
+					n := len(*stack) - 1
+					w := (*stack)[n]
+					*stack = (*stack)[:n]
+					onStack[w] = false
+
+					scc = append(scc, w)
+					if w == v {
+						break
+					}
+				}
+
+				*sccs = append(*sccs, scc)
+			}
+
+			if len(frames) > 0 {
+				parent := frames[len(frames)-1].node
+				if lowlink[v] < lowlink[parent] {
+					lowlink[parent] = lowlink[v]
+				}
+			}
+
+			continue
+		}
+
+		w := frame.neighbors[frame.next]
+		frame.next++
+
+		if _, visited := indices[w]; !visited {
+			visit(w)
+			frames = append(frames, &strongconnectFrame{node: w, neighbors: setKeys(cg.nodes[w].AllOutputs())})
+		} else if onStack[w] {
+			if indices[w] < lowlink[v] {
+				lowlink[v] = indices[w]
+			}
+		}
+	}
+}
+
+// setKeys returns the members of a strset as a slice, so a neighbor list
+// can be walked by index across repeated visits to the same frame.
+func setKeys(s strset) []string {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+//
+// IsRecursive reports whether `name` participates in a cycle: either it's
+// one of several members of a strongly connected component, or it calls
+// itself directly.
+//
+func (cg CallGraph) IsRecursive(name string) bool {
+	for _, scc := range cg.StronglyConnectedComponents() {
+		member := false
+		for _, m := range scc {
+			if m == name {
+				member = true
+				break
+			}
+		}
+		if !member {
+			continue
+		}
+
+		if len(scc) > 1 {
+			return true
+		}
+
+		for _, call := range cg.nodes[name].CallsOut {
+			if call.Callee == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	return false
+}
+
+//
+// Condense returns the condensation DAG of cg: each strongly connected
+// component of two or more nodes becomes a single synthetic GraphNode
+// (Kind == NodeCondensed, Function == "SCC(N members)"), edges between
+// components are preserved with summed weights, and self-edges within a
+// component are dropped.
+//
+func (cg CallGraph) Condense() CallGraph {
+	sccs := cg.StronglyConnectedComponents()
+	result := NewCallGraph()
+
+	component := make(map[string]string, len(cg.nodes))
+
+	for _, members := range sccs {
+		if len(members) == 1 {
+			// A lone node isn't condensed: keep it as it is, minus its
+			// edges, which are re-added below.
+			n := cg.nodes[members[0]]
+			n.CallsIn, n.CallsOut, n.FlowsIn, n.FlowsOut = nil, nil, nil, nil
+			component[members[0]] = members[0]
+			result.AddNode(n)
+			continue
+		}
+
+		sorted := append([]string(nil), members...)
+		sort.Strings(sorted)
+		name := strings.Join(sorted, ",")
+
+		node := newGraphNode(CallSite{Function: fmt.Sprintf("SCC(%d members)", len(members))}, "")
+		node.Name = name
+		node.Members = sorted
+
+		if len(members) > 1 {
+			node.Kind = NodeCondensed
+		}
+
+		for _, member := range members {
+			component[member] = name
+
+			m := cg.nodes[member]
+			if node.Library == "" {
+				node.Library = m.Library
+			}
+			node.CVE = node.CVE.Union(m.CVE)
+			node.Owners = node.Owners.Union(m.Owners)
+			node.Tags = node.Tags.Union(m.Tags)
+		}
+
+		result.AddNode(node)
+	}
+
+	type edge struct{ from, to string }
+
+	callWeights := make(map[edge]int)
+	for call, weight := range cg.calls {
+		from, to := component[call.Caller], component[call.Callee]
+		if from == to {
+			continue
+		}
+		callWeights[edge{from, to}] += weight
+	}
+
+	for e, weight := range callWeights {
+		result.AddCalls(Call{Caller: e.from, Callee: e.to}, weight)
+	}
+
+	flowWeights := make(map[edge]int)
+	for flow, weight := range cg.flows {
+		from, to := component[flow.Caller], component[flow.Callee]
+		if from == to {
+			continue
+		}
+		flowWeights[edge{from, to}] += weight
+	}
+
+	for e, weight := range flowWeights {
+		result.AddFlows(Call{Caller: e.from, Callee: e.to}, weight)
+	}
+
+	return result
+}
@@ -0,0 +1,100 @@
+package soaap
+
+import (
+	"context"
+	"fmt"
+)
+
+//
+// Build the complete call graph implied by every CallTrace in these Results,
+// independent of which Vuln or PrivAccess entries happen to reference them.
+//
+// This amounts to inverting the trace structure once: each CallSite's
+// GraphNode ends up with a full CallsIn/CallsOut record of every caller and
+// callee observed anywhere in the traces, rather than only those seen while
+// walking a single vulnerability's own (possibly truncated) trace.
+//
+func (r Results) BuildReverseIndex() (CallGraph, error) {
+	graph := NewCallGraph()
+
+	for i := range r.Traces {
+		var callee *GraphNode
+
+		err := r.Traces[i].ForeachN(r.Traces, DefaultMaxTraceDepth, func(cs CallSite) {
+			caller := newGraphNode(cs, "")
+			graph.AddNode(caller)
+
+			if callee != nil {
+				graph.AddCall(newCall(caller, *callee, cs, ""))
+			}
+
+			c := caller
+			callee = &c
+		})
+
+		if err != nil {
+			return CallGraph{}, fmt.Errorf("trace %d: %w", i, err)
+		}
+	}
+
+	return graph, nil
+}
+
+//
+// ExtractVulnGraph builds the call graph of entry-to-vulnerability paths by
+// intersecting a backward slice (everything that can reach a vulnerability
+// sink) with a forward slice (everything reachable from a program entry
+// point), via CallGraph.SliceIntersect over the complete trace graph (see
+// BuildReverseIndex).
+//
+// VulnGraph walks each vulnerability's own trace top-down, which can miss
+// edges in the presence of recursion: given G <-> F -> V with F an entry
+// point and V vulnerable, the top-down walk may add F -> V but fail to add
+// the G <-> F cycle when it was visited before V was known to be
+// vulnerable. Building the graph as a slice intersection over the complete
+// trace graph instead guarantees that every entry-to-vulnerability path,
+// including (F -> G)+ -> V, is represented - the same fixed-point approach
+// VulnGraph/PrivAccessGraph use, rather than a second, independent one.
+//
+func ExtractVulnGraph(ctx context.Context, results Results, progress ProgressReporter) (CallGraph, error) {
+	progress.Start(0, "Building reverse index")
+
+	merged, err := results.BuildReverseIndex()
+	if err != nil {
+		progress.Finish()
+		return CallGraph{}, err
+	}
+
+	progress.Add(int64(len(merged.nodes)))
+	progress.Finish()
+
+	if err := ctx.Err(); err != nil {
+		return CallGraph{}, err
+	}
+
+	// BuildReverseIndex names every node with an empty sandbox, so sinks
+	// are named the same way in order to be found in merged.
+	sinks := make(strset)
+	for _, v := range results.Vulnerabilities {
+		sinks.Add(newGraphNode(v.CallSite, "").Name)
+	}
+
+	graph := merged.SliceIntersect(merged.roots, sinks)
+
+	for id, node := range graph.nodes {
+		if !sinks.Contains(id) {
+			continue
+		}
+
+		for _, v := range results.Vulnerabilities {
+			if newGraphNode(v.CallSite, "").Name == id {
+				node.CVE = node.CVE.Union(v.CVEs())
+				node.Sandbox = v.Sandbox
+			}
+		}
+
+		graph.AddNode(node)
+	}
+
+	return graph, nil
+}
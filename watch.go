@@ -0,0 +1,112 @@
+package soaap
+
+import (
+	"os"
+	"time"
+)
+
+//
+// WatchFiles polls `paths` for mtime/size changes every 100ms, debounced by
+// `delay`: once a change is observed, nothing is sent until `delay` passes
+// with no further change, so a burst of writes (e.g. an editor's
+// save-via-rename, or a SOAAP run that touches its output file more than
+// once) collapses into a single event rather than triggering a re-run per
+// write.
+//
+// fsnotify was the library asked for; it isn't vendored into this module
+// (see fetch.go's hand-rolled HTTP/S3 clients for the same stdlib-only
+// tradeoff), so this polls rather than subscribing to filesystem change
+// notifications directly. Polling every 100ms is fine for a handful of
+// locally-mounted input files, but it won't behave the same as fsnotify
+// on a network filesystem or under heavy write load, and nothing here
+// has been checked against those cases. Flag for sign-off rather than
+// treating the poll loop as a drop-in substitute.
+//
+// The returned channel is closed once `stop` is closed.
+//
+func WatchFiles(paths []string, delay time.Duration, stop <-chan struct{}) <-chan struct{} {
+	events := make(chan struct{})
+
+	go func() {
+		defer close(events)
+
+		const pollInterval = 100 * time.Millisecond
+
+		last := statAll(paths)
+
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		var debounce *time.Timer
+		var debounceC <-chan time.Time
+
+		for {
+			select {
+			case <-stop:
+				return
+
+			case <-ticker.C:
+				cur := statAll(paths)
+				if !sameStats(cur, last) {
+					if debounce == nil {
+						debounce = time.NewTimer(delay)
+					} else {
+						if !debounce.Stop() {
+							<-debounce.C
+						}
+						debounce.Reset(delay)
+					}
+					debounceC = debounce.C
+				}
+				last = cur
+
+			case <-debounceC:
+				// The timer has already fired and drained its channel, so
+				// it can't be Stop/Reset-ed again: forget it entirely
+				// rather than just debounceC, or the next change re-arms
+				// this stale, already-drained timer and blocks forever on
+				// <-debounce.C below.
+				debounce = nil
+				debounceC = nil
+				select {
+				case events <- struct{}{}:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+
+	return events
+}
+
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+func statAll(paths []string) map[string]fileStat {
+	m := make(map[string]fileStat, len(paths))
+
+	for _, p := range paths {
+		if info, err := os.Stat(p); err == nil {
+			m[p] = fileStat{info.ModTime(), info.Size()}
+		}
+	}
+
+	return m
+}
+
+func sameStats(a, b map[string]fileStat) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for p, s := range a {
+		if b[p] != s {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,153 @@
+package soaap
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// The GUID RFC 6455 §1.3 has every WebSocket server append to the client's
+// Sec-WebSocket-Key before hashing it, to prove the handshake wasn't
+// produced by a plain HTTP cache or proxy that doesn't understand upgrades.
+const wsHandshakeGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+//
+// WSHub is a minimal, hand-rolled RFC 6455 WebSocket server, good enough
+// for -serve's live-reload notifications: it only ever pushes a one-way
+// "reload" text frame out to connected browser tabs, so unlike a
+// general-purpose library (gorilla/websocket isn't vendored into this
+// module - see fetch.go for the same stdlib-only tradeoff made for HTTP/S3)
+// it doesn't support fragmented messages, extensions, or reading anything
+// back from the client beyond noticing that it disconnected. Reimplementing
+// wire-format/protocol code in-house like this is exactly where subtle bugs
+// hide, so this needs sign-off from whoever owns this backlog before
+// merging, not a silent "equivalent" substitution for gorilla/websocket.
+//
+type WSHub struct {
+	mu    sync.Mutex
+	conns map[net.Conn]bool
+}
+
+func NewWSHub() *WSHub {
+	return &WSHub{conns: make(map[net.Conn]bool)}
+}
+
+// Upgrade promotes an HTTP request to a WebSocket connection and registers
+// it with the hub. The caller's handler should return immediately
+// afterwards without writing anything else to w.
+func (h *WSHub) Upgrade(w http.ResponseWriter, r *http.Request) error {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return errors.New("not a websocket upgrade request")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return errors.New("missing Sec-WebSocket-Key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return errors.New("connection doesn't support hijacking")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return err
+	}
+
+	_, err = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + wsAcceptKey(key) + "\r\n\r\n")
+	if err == nil {
+		err = rw.Flush()
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	h.mu.Lock()
+	h.conns[conn] = true
+	h.mu.Unlock()
+
+	go h.drainUntilClosed(conn, rw.Reader)
+
+	return nil
+}
+
+func wsAcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + wsHandshakeGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// drainUntilClosed discards whatever a client tab sends - this hub has
+// nothing to read back beyond control frames - until the read fails, which
+// is how it notices a closed tab and drops the connection.
+func (h *WSHub) drainUntilClosed(conn net.Conn, r *bufio.Reader) {
+	defer h.remove(conn)
+
+	buf := make([]byte, 4096)
+	for {
+		if _, err := r.Read(buf); err != nil {
+			return
+		}
+	}
+}
+
+func (h *WSHub) remove(conn net.Conn) {
+	h.mu.Lock()
+	delete(h.conns, conn)
+	h.mu.Unlock()
+	conn.Close()
+}
+
+// Broadcast sends `message` as a single WebSocket text frame to every
+// currently-connected client, dropping any connection that errors on
+// write (the client went away without a clean close).
+func (h *WSHub) Broadcast(message string) {
+	frame := wsTextFrame(message)
+
+	h.mu.Lock()
+	conns := make([]net.Conn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range conns {
+		if _, err := c.Write(frame); err != nil {
+			h.remove(c)
+		}
+	}
+}
+
+// wsTextFrame encodes `s` as a single, unmasked, unfragmented WebSocket
+// text frame. Server-to-client frames are never masked (RFC 6455 §5.1).
+func wsTextFrame(s string) []byte {
+	payload := []byte(s)
+
+	var header []byte
+	switch {
+	case len(payload) <= 125:
+		header = []byte{0x81, byte(len(payload))}
+
+	case len(payload) <= 0xFFFF:
+		header = []byte{0x81, 126, byte(len(payload) >> 8), byte(len(payload))}
+
+	default:
+		header = make([]byte, 10)
+		header[0] = 0x81
+		header[1] = 127
+		for i := 0; i < 8; i++ {
+			header[9-i] = byte(len(payload) >> (8 * i))
+		}
+	}
+
+	return append(header, payload...)
+}
@@ -0,0 +1,285 @@
+package soaap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//
+// A minimal, stdlib-only parser for the subset of YAML this module's
+// pipeline config (see pipeline.go) actually needs: block mappings, block
+// sequences (including sequences of mappings), scalar strings/ints/bools,
+// comments and blank lines, plus a one-line "[a, b]" list of scalars (see
+// parseYAMLScalar). It deliberately doesn't attempt any other flow style
+// ("{a: 1}", nested "[[1], [2]]"), anchors/aliases, multi-document streams or any of
+// YAML's other corners - gopkg.in/yaml.v3 was asked for, and isn't vendored
+// into this module (see soaappb/graph.go for the same stdlib-only tradeoff
+// made for proto). The risk here is sharper than a missing feature: a
+// config file that strays outside the supported subset either fails to
+// parse or, worse, silently parses into something other than what a real
+// YAML parser would produce, and nothing here detects that divergence.
+// Flag for sign-off before trusting this on anything but the pipeline
+// configs it was written against.
+//
+// parseYAMLLite returns a tree of map[string]interface{}, []interface{} and
+// scalars (string, bool, int, or nil for null), much as encoding/json would
+// hand back for the equivalent document, except that integers come back as
+// int rather than float64.
+//
+func parseYAMLLite(data []byte) (interface{}, error) {
+	lines := splitYAMLLines(data)
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	value, rest, err := parseYAMLBlock(lines, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("line %d: unexpected indentation", rest[0].num)
+	}
+
+	return value, nil
+}
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string // trimmed of leading whitespace, comments and trailing whitespace
+}
+
+// splitYAMLLines strips comments, blank lines and the document-start/end
+// markers ("---", "...") that a hand-written config file commonly has,
+// leaving only lines that carry content.
+func splitYAMLLines(data []byte) []yamlLine {
+	var lines []yamlLine
+
+	for i, raw := range strings.Split(string(data), "\n") {
+		text := stripYAMLComment(raw)
+		trimmed := strings.TrimRight(text, " \t\r")
+		indent := len(trimmed) - len(strings.TrimLeft(trimmed, " "))
+		content := strings.TrimLeft(trimmed, " ")
+
+		if content == "" || content == "---" || content == "..." {
+			continue
+		}
+
+		lines = append(lines, yamlLine{num: i + 1, indent: indent, text: content})
+	}
+
+	return lines
+}
+
+// stripYAMLComment removes a trailing "# ..." comment, taking care not to
+// be fooled by a '#' inside a quoted scalar.
+func stripYAMLComment(line string) string {
+	inSingle, inDouble := false, false
+
+	for i, r := range line {
+		switch {
+		case r == '\'' && !inDouble:
+			inSingle = !inSingle
+		case r == '"' && !inSingle:
+			inDouble = !inDouble
+		case r == '#' && !inSingle && !inDouble:
+			if i == 0 || line[i-1] == ' ' || line[i-1] == '\t' {
+				return line[:i]
+			}
+		}
+	}
+
+	return line
+}
+
+// parseYAMLBlock parses a run of lines at exactly `indent`, returning once a
+// line with a shallower indent is reached (the caller's block is done) or
+// the input runs out. A run of "- " lines becomes a []interface{}; anything
+// else becomes a map[string]interface{}.
+func parseYAMLBlock(lines []yamlLine, indent int) (interface{}, []yamlLine, error) {
+	if len(lines) == 0 || lines[0].indent != indent {
+		return nil, lines, nil
+	}
+
+	if strings.HasPrefix(lines[0].text, "- ") || lines[0].text == "-" {
+		return parseYAMLSequence(lines, indent)
+	}
+
+	return parseYAMLMapping(lines, indent)
+}
+
+func parseYAMLSequence(lines []yamlLine, indent int) (interface{}, []yamlLine, error) {
+	var seq []interface{}
+
+	for len(lines) > 0 && lines[0].indent == indent &&
+		(strings.HasPrefix(lines[0].text, "- ") || lines[0].text == "-") {
+
+		item := strings.TrimPrefix(lines[0].text, "-")
+		item = strings.TrimPrefix(item, " ")
+
+		if item == "" {
+			// "- " with the item's content on following, deeper-indented lines.
+			value, rest, err := parseYAMLBlock(lines[1:], indentOf(lines[1:], indent))
+			if err != nil {
+				return nil, nil, err
+			}
+			seq = append(seq, value)
+			lines = rest
+			continue
+		}
+
+		if strings.Contains(item, ":") && !looksLikeScalar(item) {
+			// "- key: value" starts an inline mapping; the rest of that
+			// mapping's keys, if any, follow at item's own indentation.
+			itemIndent := indent + (len(lines[0].text) - len(item))
+			synthetic := append([]yamlLine{{num: lines[0].num, indent: itemIndent, text: item}}, lines[1:]...)
+
+			value, rest, err := parseYAMLMapping(synthetic, itemIndent)
+			if err != nil {
+				return nil, nil, err
+			}
+			seq = append(seq, value)
+			lines = rest
+			continue
+		}
+
+		seq = append(seq, parseYAMLScalar(item))
+		lines = lines[1:]
+	}
+
+	return seq, lines, nil
+}
+
+func parseYAMLMapping(lines []yamlLine, indent int) (interface{}, []yamlLine, error) {
+	m := make(map[string]interface{})
+
+	for len(lines) > 0 && lines[0].indent == indent {
+		line := lines[0]
+
+		key, value, hasValue, err := splitYAMLKeyValue(line.text)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", line.num, err)
+		}
+
+		if hasValue {
+			m[key] = parseYAMLScalar(value)
+			lines = lines[1:]
+			continue
+		}
+
+		// The value is a nested block on following, deeper-indented lines.
+		childIndent := indentOf(lines[1:], indent)
+		if childIndent <= indent {
+			m[key] = nil
+			lines = lines[1:]
+			continue
+		}
+
+		child, rest, err := parseYAMLBlock(lines[1:], childIndent)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		m[key] = child
+		lines = rest
+	}
+
+	return m, lines, nil
+}
+
+// indentOf returns the indentation of the first line in `lines`, or -1 if
+// there isn't one (used to detect an empty/absent nested block).
+func indentOf(lines []yamlLine, fallback int) int {
+	if len(lines) == 0 {
+		return -1
+	}
+	return lines[0].indent
+}
+
+// splitYAMLKeyValue splits "key: value" into ("key", "value", true), or
+// "key:" into ("key", "", false) to signal that the value is a nested block.
+func splitYAMLKeyValue(text string) (key, value string, hasValue bool, err error) {
+	idx := strings.Index(text, ":")
+	if idx < 0 {
+		return "", "", false, fmt.Errorf("expected \"key: value\", got %q", text)
+	}
+
+	key = strings.TrimSpace(unquoteYAML(text[:idx]))
+
+	rest := strings.TrimSpace(text[idx+1:])
+	if rest == "" {
+		return key, "", false, nil
+	}
+
+	return key, rest, true, nil
+}
+
+// looksLikeScalar reports whether a "- " sequence item containing a colon
+// is a quoted scalar (e.g. "- \"http://host/path\"") rather than the start
+// of an inline mapping. Only quoting is recognized: an unquoted item such
+// as a time-like "12:30" is taken as a mapping.
+func looksLikeScalar(item string) bool {
+	return strings.HasPrefix(item, "\"") || strings.HasPrefix(item, "'")
+}
+
+func parseYAMLScalar(raw string) interface{} {
+	raw = strings.TrimSpace(raw)
+
+	if s, ok := unquoteIfQuoted(raw); ok {
+		return s
+	}
+
+	switch raw {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null", "~", "":
+		return nil
+	}
+
+	if i, err := strconv.Atoi(raw); err == nil {
+		return i
+	}
+
+	// Inline flow sequences ("[a, b]") show up often enough in hand-written
+	// configs (e.g. "with: [base, extra]") to be worth a shorthand, even
+	// though general flow style isn't supported.
+	if strings.HasPrefix(raw, "[") && strings.HasSuffix(raw, "]") {
+		inner := strings.TrimSpace(raw[1 : len(raw)-1])
+		if inner == "" {
+			return []interface{}{}
+		}
+
+		var items []interface{}
+		for _, part := range strings.Split(inner, ",") {
+			items = append(items, parseYAMLScalar(strings.TrimSpace(part)))
+		}
+		return items
+	}
+
+	return unquoteYAML(raw)
+}
+
+func unquoteIfQuoted(s string) (string, bool) {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		unquoted, err := strconv.Unquote(s)
+		if err == nil {
+			return unquoted, true
+		}
+	}
+
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), true
+	}
+
+	return "", false
+}
+
+func unquoteYAML(s string) string {
+	if unquoted, ok := unquoteIfQuoted(s); ok {
+		return unquoted
+	}
+	return s
+}